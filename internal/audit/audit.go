@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"ai-mcp/internal/consts"
+	"ai-mcp/internal/model"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"time"
+
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/net/gtrace"
+	"github.com/gogf/gf/v2/os/gfile"
+	"github.com/gogf/gf/v2/os/glog"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// logger 审计日志专用 logger，只追加写文件，不输出到终端
+var logger *glog.Logger
+
+// Init 按 audit 配置初始化审计日志，未启用时 Record 为空操作
+func Init() (err error) {
+	cfg := consts.Config.Audit
+	if cfg == nil || !cfg.Enabled {
+		return
+	}
+	path := cfg.Path
+	if path == "" {
+		path = "./logs/audit"
+	}
+	file := cfg.File
+	if file == "" {
+		file = "audit-{Y-m-d}.jsonl"
+	}
+	// 审计记录不可丢弃：glog 在备份数为 0 时按大小切分会直接删除原文件
+	backupLimit := cfg.RotateBackupLimit
+	if backupLimit <= 0 {
+		backupLimit = math.MaxInt32
+	}
+
+	l := glog.New()
+	if err = l.SetConfigWithMap(map[string]any{
+		"path":                path,
+		"file":                file,
+		"stdout":              false,
+		"header":              false,
+		"stStatus":            0,
+		"rotateSize":          cfg.RotateSize,
+		"rotateBackupLimit":   backupLimit,
+		"rotateCheckInterval": "1m",
+	}); err != nil {
+		return
+	}
+	logger = l
+	consts.Logger.Infof(consts.Ctx, "审计日志已启用，目录: %s", gfile.Abs(path))
+	return
+}
+
+// Record 写入一条工具调用审计记录
+func Record(ctx context.Context, record *model.AuditRecord, result *mcp.CallToolResult) {
+	if logger == nil {
+		return
+	}
+	record.Time = time.Now().Format("2006-01-02T15:04:05.000Z07:00")
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		record.SessionId = session.SessionID()
+	}
+	if result != nil {
+		data := []byte(gjson.MustEncodeString(result))
+		sum := sha256.Sum256(data)
+		record.ResultSize = len(data)
+		record.ResultHash = hex.EncodeToString(sum[:])
+	}
+	record.TraceId = gtrace.GetTraceID(ctx)
+	// 使用空上下文输出，避免 glog 在行首追加 trace id 破坏 JSON Lines 格式
+	logger.Print(context.Background(), gjson.MustEncodeString(record))
+}
@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"ai-mcp/internal/consts"
+	"ai-mcp/internal/model"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+type sAuth struct{}
+
+type ctxKeyIdentity struct{}
+
+var (
+	Auth = &sAuth{}
+
+	// Anonymous 未启用认证时的调用方身份
+	Anonymous = &model.Identity{Name: "anonymous", Method: "none"}
+	// Stdio stdio 模式下的本地调用方身份
+	Stdio = &model.Identity{Name: "local", Method: "stdio"}
+)
+
+// WithIdentity 将调用方身份写入上下文
+func WithIdentity(ctx context.Context, identity *model.Identity) context.Context {
+	return context.WithValue(ctx, ctxKeyIdentity{}, identity)
+}
+
+// GetIdentity 从上下文获取调用方身份，不存在时返回 nil
+func GetIdentity(ctx context.Context) *model.Identity {
+	identity, _ := ctx.Value(ctxKeyIdentity{}).(*model.Identity)
+	return identity
+}
+
+// Enabled 是否启用了认证
+func (s *sAuth) Enabled() bool {
+	return consts.Config.McpServer.Auth != nil && consts.Config.McpServer.Auth.Enabled
+}
+
+// Middleware HTTP 认证中间件，认证失败返回 401，成功后将身份写入请求上下文
+func (s *sAuth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.Enabled() {
+			identity := Anonymous
+			if certIdentity := clientCertIdentity(r); certIdentity != nil {
+				identity = certIdentity
+			}
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+			return
+		}
+		identity, err := s.Authenticate(r)
+		if err != nil {
+			consts.Logger.Warningf(r.Context(), "认证失败 %s %s 来自 %s: %s", r.Method, r.URL.Path, r.RemoteAddr, err.Error())
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ai-mcp"`)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(gjson.MustEncodeString(g.Map{"error": "unauthorized", "message": err.Error()})))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+	})
+}
+
+// Authenticate 从请求头 X-API-Key 或 Authorization: Bearer 中识别调用方，均未携带时使用已校验的客户端证书
+func (s *sAuth) Authenticate(r *http.Request) (identity *model.Identity, err error) {
+	credential := r.Header.Get("X-API-Key")
+	if credential == "" {
+		if scheme, value, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+			credential = strings.TrimSpace(value)
+		}
+	}
+	if credential == "" {
+		if identity = clientCertIdentity(r); identity == nil {
+			err = errors.New("缺少认证信息")
+		}
+		return
+	}
+
+	cfg := consts.Config.McpServer.Auth
+	for _, item := range cfg.ApiKeys {
+		if item.Key != "" && subtle.ConstantTimeCompare([]byte(item.Key), []byte(credential)) == 1 {
+			identity = &model.Identity{Name: item.Name, Method: "api-key", Profile: item.Profile}
+			return
+		}
+	}
+	if cfg.HmacSecret != "" && strings.Count(credential, ".") == 2 {
+		return verifyHmacToken(credential, []byte(cfg.HmacSecret))
+	}
+	err = errors.New("无效的认证信息")
+	return
+}
+
+// verifyHmacToken 校验 HS256 签名的 JWT，sub 作为调用方名称，profile 作为授权配置，并校验 exp/nbf
+func verifyHmacToken(token string, secret []byte) (identity *model.Identity, err error) {
+	parts := strings.Split(token, ".")
+	header, err := decodeSegment(parts[0])
+	if err != nil || header.Get("alg").String() != "HS256" {
+		err = errors.New("不支持的 Token 签名算法")
+		return
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		err = errors.New("Token 签名无效")
+		return
+	}
+
+	claims, err := decodeSegment(parts[1])
+	if err != nil {
+		err = errors.New("Token 内容无效")
+		return
+	}
+	now := time.Now().Unix()
+	if exp := claims.Get("exp").Int64(); exp > 0 && now >= exp {
+		err = errors.New("Token 已过期")
+		return
+	}
+	if nbf := claims.Get("nbf").Int64(); nbf > 0 && now < nbf {
+		err = errors.New("Token 尚未生效")
+		return
+	}
+	sub := claims.Get("sub").String()
+	if sub == "" {
+		err = errors.New("Token 缺少 sub")
+		return
+	}
+	identity = &model.Identity{Name: sub, Method: "bearer", Profile: claims.Get("profile").String()}
+	return
+}
+
+// clientCertIdentity 将双向 TLS 中已校验的客户端证书主题映射为调用方身份
+func clientCertIdentity(r *http.Request) *model.Identity {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	subject := r.TLS.VerifiedChains[0][0].Subject
+	name := subject.CommonName
+	if name == "" {
+		name = subject.String()
+	}
+	identity := &model.Identity{Name: name, Method: "tls"}
+	if cfg := consts.Config.McpServer.Tls; cfg != nil {
+		identity.Profile = cfg.ClientProfiles[name]
+	}
+	return identity
+}
+
+func decodeSegment(segment string) (*gjson.Json, error) {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return nil, err
+	}
+	return gjson.DecodeToJson(data)
+}
@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"ai-mcp/internal/consts"
+	"ai-mcp/internal/model"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+const testHmacSecret = "test-hmac-secret"
+
+// signToken 以 secret 签发 JWT，header 中的 alg 原样写入，签名固定使用 HS256
+func signToken(secret string, header, claims g.Map) string {
+	encode := func(v g.Map) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(gjson.MustEncodeString(v)))
+	}
+	payload := encode(header) + "." + encode(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// setAuthConfig 替换认证与授权配置，测试结束后恢复
+func setAuthConfig(t *testing.T, cfg *model.AuthConfig, profiles map[string]*model.ToolProfile) {
+	t.Helper()
+	authCfg, profilesCfg := consts.Config.McpServer.Auth, consts.Config.Profiles
+	consts.Config.McpServer.Auth, consts.Config.Profiles = cfg, profiles
+	t.Cleanup(func() {
+		consts.Config.McpServer.Auth, consts.Config.Profiles = authCfg, profilesCfg
+	})
+}
+
+func TestVerifyHmacToken(t *testing.T) {
+	now := time.Now().Unix()
+	hs256 := g.Map{"alg": "HS256", "typ": "JWT"}
+
+	tests := []struct {
+		name    string
+		token   string
+		want    *model.Identity
+		wantErr bool
+	}{
+		{
+			name:  "有效 Token",
+			token: signToken(testHmacSecret, hs256, g.Map{"sub": "ci", "profile": "readonly", "exp": now + 60, "nbf": now - 60}),
+			want:  &model.Identity{Name: "ci", Method: "bearer", Profile: "readonly"},
+		},
+		{
+			name:  "未设置 exp 与 nbf",
+			token: signToken(testHmacSecret, hs256, g.Map{"sub": "ci"}),
+			want:  &model.Identity{Name: "ci", Method: "bearer"},
+		},
+		{name: "alg 为 none", token: signToken(testHmacSecret, g.Map{"alg": "none"}, g.Map{"sub": "ci"}), wantErr: true},
+		{name: "alg 为 HS512", token: signToken(testHmacSecret, g.Map{"alg": "HS512"}, g.Map{"sub": "ci"}), wantErr: true},
+		{name: "缺少 alg", token: signToken(testHmacSecret, g.Map{"typ": "JWT"}, g.Map{"sub": "ci"}), wantErr: true},
+		{name: "其他密钥签名", token: signToken("other-secret", hs256, g.Map{"sub": "ci"}), wantErr: true},
+		{name: "篡改内容", token: tamperClaims(signToken(testHmacSecret, hs256, g.Map{"sub": "ci"}), g.Map{"sub": "admin"}), wantErr: true},
+		{name: "签名不是 base64url", token: signToken(testHmacSecret, hs256, g.Map{"sub": "ci"}) + "!", wantErr: true},
+		{name: "已过期", token: signToken(testHmacSecret, hs256, g.Map{"sub": "ci", "exp": now - 1}), wantErr: true},
+		{name: "尚未生效", token: signToken(testHmacSecret, hs256, g.Map{"sub": "ci", "nbf": now + 60}), wantErr: true},
+		{name: "缺少 sub", token: signToken(testHmacSecret, hs256, g.Map{"profile": "readonly"}), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := verifyHmacToken(tt.token, []byte(testHmacSecret))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verifyHmacToken() err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && *got != *tt.want {
+				t.Errorf("verifyHmacToken() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// tamperClaims 替换 Token 的内容段，保留原签名
+func tamperClaims(token string, claims g.Map) string {
+	parts := strings.Split(token, ".")
+	parts[1] = base64.RawURLEncoding.EncodeToString([]byte(gjson.MustEncodeString(claims)))
+	return strings.Join(parts, ".")
+}
+
+func TestAuthenticate(t *testing.T) {
+	setAuthConfig(t, &model.AuthConfig{
+		Enabled:    true,
+		ApiKeys:    []model.AuthApiKey{{Name: "sre", Key: "sre-key", Profile: "ops"}},
+		HmacSecret: testHmacSecret,
+	}, nil)
+	token := signToken(testHmacSecret, g.Map{"alg": "HS256"}, g.Map{"sub": "ci"})
+	clientCert := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+		{Subject: pkix.Name{CommonName: "deploy-bot", Organization: []string{"ops"}}},
+	}}}
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		tls     *tls.ConnectionState
+		want    *model.Identity
+		wantErr bool
+	}{
+		{
+			name:   "X-API-Key",
+			header: http.Header{"X-Api-Key": {"sre-key"}},
+			want:   &model.Identity{Name: "sre", Method: "api-key", Profile: "ops"},
+		},
+		{
+			name:   "Authorization: Bearer 携带 API Key",
+			header: http.Header{"Authorization": {"Bearer sre-key"}},
+			want:   &model.Identity{Name: "sre", Method: "api-key", Profile: "ops"},
+		},
+		{
+			name:   "Bearer 大小写不敏感",
+			header: http.Header{"Authorization": {"bearer sre-key"}},
+			want:   &model.Identity{Name: "sre", Method: "api-key", Profile: "ops"},
+		},
+		{
+			name:   "Authorization: Bearer 携带 Token",
+			header: http.Header{"Authorization": {"Bearer " + token}},
+			want:   &model.Identity{Name: "ci", Method: "bearer"},
+		},
+		{name: "错误的 API Key", header: http.Header{"X-Api-Key": {"wrong-key"}}, wantErr: true},
+		{name: "错误的 Bearer", header: http.Header{"Authorization": {"Bearer wrong-key"}}, wantErr: true},
+		{name: "Basic 认证", header: http.Header{"Authorization": {"Basic c3JlOnNyZS1rZXk="}}, wantErr: true},
+		{name: "缺少认证信息", header: http.Header{}, wantErr: true},
+		{
+			name:   "回退到客户端证书",
+			header: http.Header{},
+			tls:    clientCert,
+			want:   &model.Identity{Name: "deploy-bot", Method: "tls"},
+		},
+		{name: "携带错误凭据时不回退到客户端证书", header: http.Header{"X-Api-Key": {"wrong-key"}}, tls: clientCert, wantErr: true},
+		{name: "未校验的客户端证书", header: http.Header{}, tls: &tls.ConnectionState{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			req.Header = tt.header
+			req.TLS = tt.tls
+			got, err := Auth.Authenticate(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Authenticate() err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && *got != *tt.want {
+				t.Errorf("Authenticate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticateMiddleware(t *testing.T) {
+	setAuthConfig(t, &model.AuthConfig{
+		Enabled: true,
+		ApiKeys: []model.AuthApiKey{{Name: "sre", Key: "sre-key"}},
+	}, nil)
+	var got *model.Identity
+	handler := Auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = GetIdentity(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("未认证请求 = %d，WWW-Authenticate %q，期望 401", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+
+	req.Header.Set("X-API-Key", "sre-key")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || got.String() != "api-key:sre" {
+		t.Errorf("已认证请求 = %d，身份 %s，期望 200 与 api-key:sre", rec.Code, got)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	setAuthConfig(t, &model.AuthConfig{Enabled: true, HmacSecret: testHmacSecret}, map[string]*model.ToolProfile{
+		"readonly": {Allow: []string{"Get*"}, Deny: []string{"GetSecret"}},
+	})
+
+	tests := []struct {
+		name    string
+		profile string
+		tool    string
+		want    bool
+	}{
+		{name: "未指定授权配置不限制", tool: "SQL_Actuator", want: true},
+		{name: "allow 命中", profile: "readonly", tool: "GetTime", want: true},
+		{name: "allow 未命中", profile: "readonly", tool: "SQL_Actuator", want: false},
+		{name: "deny 优先", profile: "readonly", tool: "GetSecret", want: false},
+		{name: "不存在的授权配置拒绝全部", profile: "unknown", tool: "GetTime", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 授权配置来自 Token 的 profile 声明
+			claims := g.Map{"sub": "ci"}
+			if tt.profile != "" {
+				claims["profile"] = tt.profile
+			}
+			identity, err := verifyHmacToken(signToken(testHmacSecret, g.Map{"alg": "HS256"}, claims), []byte(testHmacSecret))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Auth.ToolAllowed(WithIdentity(context.Background(), identity), tt.tool); got != tt.want {
+				t.Errorf("ToolAllowed(%s, %s) = %t, want %t", tt.profile, tt.tool, got, tt.want)
+			}
+		})
+	}
+}
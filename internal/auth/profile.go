@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"ai-mcp/internal/consts"
+	"ai-mcp/internal/model"
+	"ai-mcp/utility"
+	"context"
+)
+
+// Profile 获取上下文中调用方的授权配置，返回 nil 表示不限制
+func (s *sAuth) Profile(ctx context.Context) *model.ToolProfile {
+	name := ""
+	if identity := GetIdentity(ctx); identity != nil {
+		name = identity.Profile
+	}
+	if name == "" && consts.Config.McpServer.Auth != nil {
+		name = consts.Config.McpServer.Auth.DefaultProfile
+	}
+	if name == "" {
+		return nil
+	}
+	if profile, ok := consts.Config.Profiles[name]; ok && profile != nil {
+		return profile
+	}
+	// 引用了不存在的配置时按拒绝全部处理，避免配置错误导致权限放大
+	consts.Logger.Warningf(ctx, "授权配置 %s 不存在，拒绝全部工具", name)
+	return &model.ToolProfile{Deny: []string{"*"}}
+}
+
+// ToolAllowed 判断调用方是否有权使用指定工具
+func (s *sAuth) ToolAllowed(ctx context.Context, toolName string) bool {
+	profile := s.Profile(ctx)
+	if profile == nil {
+		return true
+	}
+	if utility.MatchAny(profile.Deny, toolName) {
+		return false
+	}
+	return len(profile.Allow) == 0 || utility.MatchAny(profile.Allow, toolName)
+}
@@ -2,6 +2,7 @@ package consts
 
 import (
 	"ai-mcp/internal/model"
+	"sort"
 
 	"github.com/gogf/gf/v2/encoding/gjson"
 	"github.com/gogf/gf/v2/frame/g"
@@ -32,3 +33,21 @@ func initSystemConfig() (err error) {
 	}
 	return
 }
+
+// DbGroups 获取 config.yaml 中 database 节点下配置的全部分组名
+func DbGroups() []string {
+	return configGroups(ResourceDatabase)
+}
+
+// RedisGroups 获取 config.yaml 中 redis 节点下配置的全部分组名
+func RedisGroups() []string {
+	return configGroups(ResourceRedis)
+}
+
+func configGroups(node string) (groups []string) {
+	for name := range SystemConfig.Get(node).Map() {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+	return
+}
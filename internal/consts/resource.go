@@ -0,0 +1,7 @@
+package consts
+
+// 工具依赖的后端资源，对应 config.yaml 中的配置节点
+const (
+	ResourceDatabase = "database"
+	ResourceRedis    = "redis"
+)
@@ -0,0 +1,9 @@
+package consts
+
+// MCP 服务传输方式
+const (
+	TransportStdio          = "stdio"
+	TransportSSE            = "sse"
+	TransportStreamableHTTP = "streamable-http"
+	TransportHTTP           = "http" // SSE 与 Streamable HTTP 共用同一监听地址
+)
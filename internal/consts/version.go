@@ -0,0 +1,8 @@
+package consts
+
+// 构建信息，通过 -ldflags "-X ai-mcp/internal/consts.Version=..." 在构建时注入
+var (
+	Version   = "1.0.0"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
@@ -0,0 +1,92 @@
+package mcp
+
+import (
+	"ai-mcp/internal/auth"
+	"ai-mcp/internal/consts"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// requestIdHeader 由 OnBeforeCallTool 钩子写入工具请求，处理函数据此关联客户端的取消通知
+const requestIdHeader = "X-Ai-Mcp-Request-Id"
+
+// pendingCalls 进行中的工具调用，键为 会话 ID + 请求 ID，值为取消函数
+var pendingCalls sync.Map
+
+// RegisterHooks 注册工具调用钩子，将 JSON-RPC 请求 ID 传递给处理函数
+func (s *sMcpHandler) RegisterHooks(hooks *server.Hooks) {
+	hooks.AddBeforeCallTool(func(ctx context.Context, id any, message *mcp.CallToolRequest) {
+		if message.Header == nil {
+			message.Header = make(map[string][]string)
+		}
+		// 始终覆盖，避免 HTTP 客户端伪造请求头取消他人的调用
+		message.Header.Set(requestIdHeader, requestIdString(id))
+	})
+}
+
+// HandleCancelled 处理客户端发送的 notifications/cancelled，取消对应工具调用的上下文
+func (s *sMcpHandler) HandleCancelled(ctx context.Context, notification mcp.JSONRPCNotification) {
+	var params mcp.CancelledNotificationParams
+	data, err := json.Marshal(notification.Params)
+	if err == nil {
+		err = json.Unmarshal(data, &params)
+	}
+	if err != nil {
+		consts.Logger.Warningf(ctx, "无法解析取消通知: %s", err.Error())
+		return
+	}
+	key, ok := callKey(ctx, requestIdString(params.RequestId))
+	if !ok {
+		return
+	}
+	if cancel, ok := pendingCalls.LoadAndDelete(key); ok {
+		consts.Logger.Infof(ctx, "客户端取消请求 %s，原因: %s", key, params.Reason)
+		cancel.(context.CancelCauseFunc)(errCancelledByClient)
+	}
+}
+
+// errCancelledByClient 客户端取消导致的上下文取消原因
+var errCancelledByClient = errors.New("客户端已取消请求")
+
+// cancelable 登记可被客户端取消的工具调用，返回的 done 在调用结束时释放登记
+func (s *sMcpHandler) cancelable(ctx context.Context, request mcp.CallToolRequest) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	key, ok := callKey(ctx, request.Header.Get(requestIdHeader))
+	if !ok {
+		return ctx, func() { cancel(nil) }
+	}
+	pendingCalls.Store(key, cancel)
+	return ctx, func() {
+		pendingCalls.Delete(key)
+		cancel(nil)
+	}
+}
+
+// callKey 生成取消登记的键：会话 ID + 调用方 + 请求 ID。无状态 Streamable HTTP 没有会话 ID，
+// 所有客户端共用同一键空间，只能按调用方区分；此时未认证的匿名调用方无法区分彼此，不支持取消
+func callKey(ctx context.Context, id string) (key string, ok bool) {
+	if id == "" {
+		return
+	}
+	sessionId := ""
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		sessionId = session.SessionID()
+	}
+	identity := auth.GetIdentity(ctx)
+	if sessionId == "" && (identity == nil || identity == auth.Anonymous) {
+		return
+	}
+	return sessionId + "/" + identity.String() + "/" + id, true
+}
+
+func requestIdString(id any) string {
+	if requestId, ok := id.(mcp.RequestId); ok {
+		return requestId.String()
+	}
+	return mcp.NewRequestId(id).String()
+}
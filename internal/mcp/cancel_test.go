@@ -0,0 +1,27 @@
+package mcp
+
+import (
+	"ai-mcp/internal/auth"
+	"ai-mcp/internal/model"
+	"context"
+	"testing"
+)
+
+func TestCallKeyWithoutSession(t *testing.T) {
+	// 无状态 Streamable HTTP 没有会话 ID，匿名调用方无法区分彼此，不登记取消
+	if _, ok := callKey(auth.WithIdentity(context.Background(), auth.Anonymous), "1"); ok {
+		t.Error("匿名调用方在无会话时不应支持取消")
+	}
+	if _, ok := callKey(context.Background(), "1"); ok {
+		t.Error("未知调用方在无会话时不应支持取消")
+	}
+
+	alice, ok := callKey(auth.WithIdentity(context.Background(), &model.Identity{Name: "alice", Method: "api-key"}), "1")
+	if !ok {
+		t.Fatal("已认证的调用方应支持取消")
+	}
+	bob, _ := callKey(auth.WithIdentity(context.Background(), &model.Identity{Name: "bob", Method: "api-key"}), "1")
+	if alice == bob {
+		t.Errorf("不同调用方相同请求 ID 的键相同: %s", alice)
+	}
+}
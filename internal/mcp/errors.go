@@ -0,0 +1,50 @@
+package mcp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// 工具错误码，随错误结果一起返回，客户端据此区分失败类型，取值保持稳定
+const (
+	ErrCodeInvalidArgument    = "INVALID_ARGUMENT"     // 参数缺失或格式错误
+	ErrCodeDbQueryFailed      = "DB_QUERY_FAILED"      // SQL 执行失败
+	ErrCodeDbUnavailable      = "DB_UNAVAILABLE"       // 数据库未配置或无法连接
+	ErrCodeReadonlyViolation  = "READONLY_VIOLATION"   // 只读模式下执行了写语句
+	ErrCodeRedisUnavailable   = "REDIS_UNAVAILABLE"    // Redis 无法连接
+	ErrCodeRedisCommandFailed = "REDIS_COMMAND_FAILED" // Redis 命令执行失败
+	ErrCodeCommandRejected    = "COMMAND_REJECTED"     // shell 命令未通过安全校验
+	ErrCodePermissionDenied   = "PERMISSION_DENIED"    // 调用方无权使用该工具
+	ErrCodeRateLimited        = "RATE_LIMITED"         // 触发限流，_meta.retryAfterSeconds 给出重试等待时间
+	ErrCodeTimeout            = "TIMEOUT"              // 工具执行超时
+	ErrCodeCancelled          = "CANCELLED"            // 客户端取消或服务关闭取消
+	ErrCodeUnavailable        = "UNAVAILABLE"          // 服务正在关闭
+	ErrCodeInternal           = "INTERNAL_ERROR"       // 未预期的内部错误（含 panic）
+)
+
+// commonErrorCodes 中间件链与调用登记对任意工具都可能返回的错误码
+var commonErrorCodes = []string{
+	ErrCodePermissionDenied, ErrCodeRateLimited, ErrCodeTimeout, ErrCodeCancelled, ErrCodeUnavailable, ErrCodeInternal,
+}
+
+// toolError 生成错误结果：IsError 为 true，文本为 "<错误码>: <说明>"，错误码同时写入 _meta.errorCode
+func toolError(code string, format string, args ...any) *mcp.CallToolResult {
+	result := mcp.NewToolResultError(code + ": " + fmt.Sprintf(format, args...))
+	result.Meta = mcp.NewMetaFromMap(map[string]any{"errorCode": code})
+	return result
+}
+
+// withErrorCodes 在工具描述后追加错误码说明：工具自身的错误码在前，其后为 commonErrorCodes
+func withErrorCodes(description string, codes []string) string {
+	codes = slices.Clone(codes)
+	for _, code := range commonErrorCodes {
+		if !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	description = strings.TrimRight(strings.TrimSpace(description), ".。")
+	return fmt.Sprintf("%s. On failure returns isError=true with text \"<CODE>: <message>\" and _meta.errorCode; codes: %s.", description, strings.Join(codes, ", "))
+}
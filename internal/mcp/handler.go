@@ -1,13 +1,21 @@
 package mcp
 
 import (
+	"ai-mcp/internal/audit"
+	"ai-mcp/internal/auth"
 	"ai-mcp/internal/consts"
+	"ai-mcp/internal/metrics"
 	"ai-mcp/internal/model"
+	"ai-mcp/internal/redact"
+	"ai-mcp/internal/tracing"
+	"ai-mcp/utility"
 	"context"
-	"fmt"
+	"time"
 
+	"github.com/gogf/gf/v2/encoding/gjson"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type sMcpTool struct{}
@@ -20,131 +28,231 @@ var (
 )
 
 func (s *sMcpHandler) GetList() []model.McpReg {
+	// 全局只读模式下 SQL_Actuator 声明为只读工具
+	dbReadonly := consts.Config.DbConfig != nil && consts.Config.DbConfig.Readonly
 	return []model.McpReg{
 		{
-			Name:        "RunSafeShellCommand",
-			Description: "Execute a terminal command safely with blacklist, operator bans and timeout; supports limited pipes (|)",
+			Name:            "RunSafeShellCommand",
+			Description:     "Execute an allowlisted terminal command safely with per-command flag rules, operator bans and timeout; supports limited pipes (|)",
+			ErrorCodes:      []string{ErrCodeInvalidArgument, ErrCodeCommandRejected},
+			Title:           "Run Shell Command",
+			ReadOnlyHint:    false,
+			DestructiveHint: true,
+			IdempotentHint:  false,
+			OpenWorldHint:   true,
 			ToolOptions: []mcp.ToolOption{
 				mcp.WithString("command",
 					mcp.Required(),
-					mcp.Description("The terminal command to execute (supports up to 3 pipes, no redirects/logic ops)"),
+					mcp.MinLength(1),
+					mcp.Description("The terminal command to execute (allowlisted executables only, limited pipes, no redirects/logic ops)"),
 				),
-				mcp.WithString("timeoutSeconds",
+				mcp.WithNumber("timeoutSeconds",
+					Integer(),
+					mcp.Min(1),
+					mcp.Max(60),
+					mcp.DefaultNumber(10),
 					mcp.Description("Timeout seconds (default 10, max 60)"),
 				),
 				mcp.WithString("cwd",
-					mcp.Description("Optional working directory"),
+					mcp.Description("Optional working directory; relative paths resolve against the default directory and must stay inside the configured roots"),
 				),
 			},
 			Fn: McpTool.RunSafeShellCommand,
 		},
 		{
-			Name:        "Md5Encode",
-			Description: "Calculate MD5 (hex lower-case) for a given text",
+			Name:            "Md5Encode",
+			Description:     "Calculate MD5 (hex lower-case) for a given text",
+			ErrorCodes:      []string{ErrCodeInvalidArgument},
+			Title:           "MD5 Hash",
+			ReadOnlyHint:    true,
+			DestructiveHint: false,
+			IdempotentHint:  true,
+			OpenWorldHint:   false,
 			ToolOptions: []mcp.ToolOption{
+				mcp.WithOutputSchema[model.Md5EncodeOutput](),
 				mcp.WithString("text",
 					mcp.Required(),
+					mcp.MinLength(1),
 					mcp.Description("The text to hash"),
 				),
 			},
 			Fn: McpTool.Md5Encode,
 		},
 		{
-			Name:        "Base64Encode",
-			Description: "Encode text to Base64",
+			Name:            "Base64Encode",
+			Description:     "Encode text to Base64",
+			ErrorCodes:      []string{ErrCodeInvalidArgument},
+			Title:           "Base64 Encode",
+			ReadOnlyHint:    true,
+			DestructiveHint: false,
+			IdempotentHint:  true,
+			OpenWorldHint:   false,
 			ToolOptions: []mcp.ToolOption{
+				mcp.WithOutputSchema[model.Base64EncodeOutput](),
 				mcp.WithString("text",
 					mcp.Required(),
+					mcp.MinLength(1),
 					mcp.Description("Plain text to encode"),
 				),
 			},
 			Fn: McpTool.Base64Encode,
 		},
 		{
-			Name:        "Base64Decode",
-			Description: "Decode Base64 string to text",
+			Name:            "Base64Decode",
+			Description:     "Decode Base64 string to text",
+			ErrorCodes:      []string{ErrCodeInvalidArgument},
+			Title:           "Base64 Decode",
+			ReadOnlyHint:    true,
+			DestructiveHint: false,
+			IdempotentHint:  true,
+			OpenWorldHint:   false,
 			ToolOptions: []mcp.ToolOption{
+				mcp.WithOutputSchema[model.Base64DecodeOutput](),
 				mcp.WithString("data",
 					mcp.Required(),
+					mcp.MinLength(1),
 					mcp.Description("Base64-encoded data"),
 				),
 			},
 			Fn: McpTool.Base64Decode,
 		},
 		{
-			Name:        "JwtParse",
-			Description: "Parse a JWT without verifying signature; returns header and payload",
+			Name:            "JwtParse",
+			Description:     "Parse a JWT without verifying signature; returns header and payload",
+			ErrorCodes:      []string{ErrCodeInvalidArgument},
+			Title:           "Parse JWT",
+			ReadOnlyHint:    true,
+			DestructiveHint: false,
+			IdempotentHint:  true,
+			OpenWorldHint:   false,
 			ToolOptions: []mcp.ToolOption{
+				mcp.WithOutputSchema[model.JwtParseOutput](),
 				mcp.WithString("token",
 					mcp.Required(),
+					mcp.MinLength(1),
 					mcp.Description("The JWT token"),
 				),
 			},
 			Fn: McpTool.JwtParse,
 		},
 		{
-			Name:        "JsonEncode",
-			Description: "Validate and compact a JSON string",
+			Name:            "JsonEncode",
+			Description:     "Validate and compact a JSON string",
+			ErrorCodes:      []string{ErrCodeInvalidArgument},
+			Title:           "Compact JSON",
+			ReadOnlyHint:    true,
+			DestructiveHint: false,
+			IdempotentHint:  true,
+			OpenWorldHint:   false,
 			ToolOptions: []mcp.ToolOption{
+				mcp.WithOutputSchema[model.JsonEncodeOutput](),
 				mcp.WithString("raw",
 					mcp.Required(),
+					mcp.MinLength(1),
 					mcp.Description("Raw JSON string to validate and compact"),
 				),
 			},
 			Fn: McpTool.JsonEncode,
 		},
 		{
-			Name:        "SQL_Actuator",
-			Description: "Convert the user's requirements into SQL statements, execute the SQL statements, and return the execution results",
+			Name:            "SQL_Actuator",
+			Requires:        consts.ResourceDatabase,
+			Description:     "Convert the user's requirements into SQL statements, execute the SQL statements, and return the execution results",
+			ErrorCodes:      []string{ErrCodeInvalidArgument, ErrCodeReadonlyViolation, ErrCodeDbQueryFailed},
+			Title:           "Execute SQL",
+			ReadOnlyHint:    dbReadonly,
+			DestructiveHint: !dbReadonly,
+			IdempotentHint:  false,
+			OpenWorldHint:   false,
 			ToolOptions: []mcp.ToolOption{
+				mcp.WithOutputSchema[model.SqlOutput](),
 				mcp.WithString("sql",
 					mcp.Required(),
+					mcp.MinLength(1),
 					mcp.Description("The SQL statement to be executed"),
 				),
 			},
 			Fn: McpTool.ExecSql,
 		},
 		{
-			Name:        "NowTime",
-			Description: "Obtain the current time information，Return the timestamp and date time in the specified time zone",
+			Name:            "NowTime",
+			Description:     "Obtain the current time information，Return the timestamp and date time in the specified time zone",
+			ErrorCodes:      []string{ErrCodeInvalidArgument},
+			Title:           "Current Time",
+			ReadOnlyHint:    true,
+			DestructiveHint: false,
+			IdempotentHint:  false,
+			OpenWorldHint:   false,
 			ToolOptions: []mcp.ToolOption{
+				mcp.WithOutputSchema[model.NowTimeOutput](),
 				mcp.WithString("timeZone",
 					mcp.Required(),
-					mcp.Description("The time zone to be used (e.g. 'Asia/Shanghai')"),
+					mcp.Enum(timeZones...),
+					mcp.Description("The IANA time zone to be used (e.g. 'Asia/Shanghai')"),
 				),
 			},
 			Fn: McpTool.GetNowTime,
 		},
 		{
-			Name:        "TimestampToDateTime",
-			Description: "Convert a timestamp to a date and time",
+			Name:            "TimestampToDateTime",
+			Description:     "Convert a timestamp to a date and time",
+			ErrorCodes:      []string{ErrCodeInvalidArgument},
+			Title:           "Timestamp to Date Time",
+			ReadOnlyHint:    true,
+			DestructiveHint: false,
+			IdempotentHint:  true,
+			OpenWorldHint:   false,
 			ToolOptions: []mcp.ToolOption{
-				mcp.WithString("timestamp",
+				mcp.WithOutputSchema[model.TimestampToDateTimeOutput](),
+				mcp.WithNumber("timestamp",
+					Integer(),
 					mcp.Required(),
-					mcp.Description("The timestamp to be converted"),
+					mcp.Min(1),
+					mcp.Description("The Unix timestamp to be converted (seconds or milliseconds)"),
 				),
 			},
 			Fn: McpTool.TimestampToDateTime,
 		},
 		{
-			Name:        "GetCalendarDays",
-			Description: "Get all days of a specified year and month",
+			Name:            "GetCalendarDays",
+			Description:     "Get all days of a specified year and month",
+			ErrorCodes:      []string{ErrCodeInvalidArgument},
+			Title:           "Calendar Days",
+			ReadOnlyHint:    true,
+			DestructiveHint: false,
+			IdempotentHint:  true,
+			OpenWorldHint:   false,
 			ToolOptions: []mcp.ToolOption{
-				mcp.WithString("year",
+				mcp.WithOutputSchema[model.CalendarDaysOutput](),
+				mcp.WithNumber("year",
+					Integer(),
 					mcp.Required(),
+					mcp.Min(1),
+					mcp.Max(9999),
 					mcp.Description("The year (e.g. 2024)"),
 				),
-				mcp.WithString("month",
+				mcp.WithNumber("month",
+					Integer(),
 					mcp.Required(),
+					mcp.Min(1),
+					mcp.Max(12),
 					mcp.Description("The month (1-12)"),
 				),
 			},
 			Fn: McpTool.GetCalendarDays,
 		},
 		{
-			Name:        "GetDatabaseInfo",
-			Description: "Get database information including type, name, and connection details",
+			Name:            "GetDatabaseInfo",
+			Requires:        consts.ResourceDatabase,
+			Description:     "Get database information including type, name, and connection details",
+			ErrorCodes:      []string{ErrCodeDbUnavailable},
+			Title:           "Database Info",
+			ReadOnlyHint:    true,
+			DestructiveHint: false,
+			IdempotentHint:  true,
+			OpenWorldHint:   false,
 			ToolOptions: []mcp.ToolOption{
+				mcp.WithOutputSchema[model.DatabaseInfoOutput](),
 				mcp.WithString("dbname",
 					mcp.Description("The database name to query (optional, uses default if not provided)"),
 				),
@@ -152,15 +260,25 @@ func (s *sMcpHandler) GetList() []model.McpReg {
 			Fn: McpTool.GetDatabaseInfo,
 		},
 		{
-			Name:        "ExecRedisCommand",
-			Description: "Execute a Redis command and return the result",
+			Name:            "ExecRedisCommand",
+			Requires:        consts.ResourceRedis,
+			Description:     "Execute a Redis command and return the result",
+			ErrorCodes:      []string{ErrCodeInvalidArgument, ErrCodeRedisUnavailable, ErrCodeRedisCommandFailed},
+			Title:           "Execute Redis Command",
+			ReadOnlyHint:    false,
+			DestructiveHint: true,
+			IdempotentHint:  false,
+			OpenWorldHint:   false,
 			ToolOptions: []mcp.ToolOption{
+				mcp.WithOutputSchema[model.RedisOutput](),
 				mcp.WithString("command",
 					mcp.Required(),
-					mcp.Description("The Redis command to execute (e.g., 'GET', 'SET', 'HGETALL', 'KEYS')"),
+					mcp.Enum(redisCommands...),
+					mcp.Description("The Redis command to execute (e.g., 'GET', 'SET', 'HGETALL', 'KEYS'); lower-case names are also accepted"),
 				),
-				mcp.WithString("args",
-					mcp.Description("Command arguments as JSON array (e.g., '[\"key\"]', '[\"key\", \"value\"]')"),
+				mcp.WithArray("args",
+					mcp.WithStringItems(),
+					mcp.Description("Command arguments (e.g., [\"key\"], [\"key\", \"value\"]); a JSON array string is also accepted"),
 				),
 			},
 			Fn: McpTool.ExecRedisCommand,
@@ -168,22 +286,119 @@ func (s *sMcpHandler) GetList() []model.McpReg {
 	}
 }
 
+// GetEnabledList 按 tools 配置过滤工具列表并应用描述覆盖，依赖资源未配置的工具自动跳过
+func (s *sMcpHandler) GetEnabledList() (list []model.McpReg) {
+	cfg := consts.Config.Tools
+	if cfg == nil {
+		cfg = &model.ToolsConfig{}
+	}
+	for _, item := range s.GetList() {
+		if utility.MatchAny(cfg.Disabled, item.Name) || (len(cfg.Enabled) > 0 && !utility.MatchAny(cfg.Enabled, item.Name)) {
+			consts.Logger.Infof(consts.Ctx, "工具 %s 已在配置中禁用，跳过注册", item.Name)
+			continue
+		}
+		if item.Requires != "" && consts.SystemConfig.Get(item.Requires).IsEmpty() {
+			consts.Logger.Infof(consts.Ctx, "工具 %s 依赖的 %s 未配置，跳过注册", item.Name, item.Requires)
+			continue
+		}
+		if override, ok := cfg.Overrides[item.Name]; ok && override != nil && override.Description != "" {
+			item.Description = override.Description
+		}
+		item.Description = withErrorCodes(item.Description, item.ErrorCodes)
+		list = append(list, item)
+	}
+	return
+}
+
+// NewTool 根据注册信息生成 MCP 工具定义，包含描述、注解与参数
+func (s *sMcpHandler) NewTool(item *model.McpReg) mcp.Tool {
+	return mcp.NewTool(item.Name, append([]mcp.ToolOption{
+		mcp.WithDescription(item.Description),
+		mcp.WithTitleAnnotation(item.Title),
+		mcp.WithReadOnlyHintAnnotation(item.ReadOnlyHint),
+		mcp.WithDestructiveHintAnnotation(item.DestructiveHint),
+		mcp.WithIdempotentHintAnnotation(item.IdempotentHint),
+		mcp.WithOpenWorldHintAnnotation(item.OpenWorldHint),
+	}, item.ToolOptions...)...)
+}
+
+// GetMcpFn 生成工具处理函数：外层负责指标、链路追踪、审计与优雅关闭登记，内层为 middleware.chain 组装的中间件链
 func (s *sMcpHandler) GetMcpFn(item *model.McpReg) (fn server.ToolHandlerFunc) {
+	handler := item.Fn
+	if handler == nil {
+		handler = func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return toolError(ErrCodeInternal, "处理函数未定义"), nil
+		}
+	}
+	// 参数校验位于中间件链内层，被拒绝或限流的调用不做校验
+	handler = validating(s.NewTool(item).InputSchema, handler)
+	// 无论 middleware.chain 是否包含 auth 或其是否被 RegisterMiddleware 覆盖，执行工具前始终校验授权配置
+	handler = authMiddleware(handler)
+	handler = wrap(handler, s.chain())
 	return func(ctx context.Context, request mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+		start := time.Now()
+		state := newCallState()
+		ctx = withCallState(ctx, state)
+		ctx, span := tracing.Start(ctx, "mcp.tool/"+item.Name,
+			attribute.String("mcp.tool.name", item.Name),
+			attribute.String("mcp.caller", auth.GetIdentity(ctx).String()),
+			attribute.String("mcp.tool.arguments", gjson.MustEncodeString(redact.Args(item.Name, request.GetArguments()))),
+		)
 		defer func() {
-			if err := recover(); err != nil {
-				consts.Logger.Printf(ctx, "panic error %+v", err)
-				fn = func(_ context.Context, _ mcp.CallToolRequest) (out *mcp.CallToolResult, outErr error) {
-					return mcp.NewToolResultText(fmt.Sprintf("panic error %+v", err)), nil
-				}
+			outcome := state.Outcome()
+			if outcome == metrics.OutcomeSuccess && (err != nil || (result != nil && result.IsError)) {
+				outcome = metrics.OutcomeToolError
+			}
+			duration := time.Since(start)
+			metrics.ObserveToolCall(item.Name, auth.GetIdentity(ctx).String(), outcome, duration)
+			tracing.End(span, outcome, err)
+			record := &model.AuditRecord{
+				Caller:     auth.GetIdentity(ctx).String(),
+				Tool:       item.Name,
+				Arguments:  redact.Args(item.Name, request.GetArguments()),
+				DurationMs: duration.Milliseconds(),
+				Outcome:    outcome,
+			}
+			if err != nil {
+				record.Error = redact.String(err.Error())
+			} else if panicMsg := state.Panic(); panicMsg != "" {
+				record.Error = redact.String(panicMsg)
+			}
+			audit.Record(ctx, record, result)
+		}()
+		// middleware.chain 未包含 recovery 或 panic 发生在其外层中间件时兜底，避免进程退出
+		defer func() {
+			if r := recover(); r != nil {
+				result, err = recoverResult(ctx, item.Name, r), nil
 			}
 		}()
-		consts.Logger.Printf(ctx, "使用工具 %s 请求内容 %+v", item.Name, request.Params.Arguments)
-		if item.Fn == nil {
-			return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-				return mcp.NewToolResultText("处理函数未定义"), nil
-			}(ctx, request)
+		ctx, done, ok := s.begin(ctx)
+		if !ok {
+			return toolError(ErrCodeUnavailable, "服务正在关闭，拒绝新的工具调用"), nil
+		}
+		defer afterHandler(ctx, done)
+		ctx, release := s.cancelable(ctx, request)
+		defer release()
+		return handler(ctx, request)
+	}
+}
+
+// ToolFilter 按调用方授权配置过滤 tools/list 返回的工具，并按调用方的 SQL 只读限制调整注解
+func (s *sMcpHandler) ToolFilter(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	allowed := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if !auth.Auth.ToolAllowed(ctx, tool.Name) {
+			continue
+		}
+		// 调用方授权配置限定 SQL 只读时，向该调用方声明 SQL_Actuator 为只读工具
+		if tool.Name == "SQL_Actuator" {
+			if profile := auth.Auth.Profile(ctx); profile != nil && profile.SqlReadonly {
+				readOnly, destructive := true, false
+				tool.Annotations.ReadOnlyHint = &readOnly
+				tool.Annotations.DestructiveHint = &destructive
+			}
 		}
-		return item.Fn(ctx, request)
+		allowed = append(allowed, tool)
 	}
+	return allowed
 }
@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"ai-mcp/internal/auth"
+	"ai-mcp/internal/consts"
+	"ai-mcp/internal/model"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// setChain 以指定的 middleware.chain 重新组装中间件链，测试结束后恢复
+func setChain(t *testing.T, names []string, custom map[string]Middleware) {
+	t.Helper()
+	cfg := consts.Config.Middleware
+	consts.Config.Middleware = &model.MiddlewareConfig{Chain: names}
+	if cfg != nil {
+		consts.Config.Middleware.Timeout = cfg.Timeout
+	}
+	customMiddlewares = custom
+	chainOnce, chainList = sync.Once{}, nil
+	t.Cleanup(func() {
+		consts.Config.Middleware = cfg
+		customMiddlewares = map[string]Middleware{}
+		chainOnce, chainList = sync.Once{}, nil
+	})
+}
+
+func callTool(fn server.ToolHandlerFunc, ctx context.Context, name string, args map[string]any) (*mcp.CallToolResult, error) {
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = args
+	return fn(ctx, request)
+}
+
+func TestGetMcpFnEnforcesProfile(t *testing.T) {
+	profiles := consts.Config.Profiles
+	consts.Config.Profiles = map[string]*model.ToolProfile{"codec": {Allow: []string{"Base64*"}}}
+	t.Cleanup(func() {
+		consts.Config.Profiles = profiles
+	})
+	passthrough := func(next server.ToolHandlerFunc) server.ToolHandlerFunc { return next }
+
+	tests := []struct {
+		name   string
+		chain  []string
+		custom map[string]Middleware
+	}{
+		{name: "默认链", chain: defaultChain},
+		{name: "链中不含 auth", chain: []string{MiddlewareRecovery, MiddlewareTimeout}},
+		{name: "auth 被同名中间件覆盖", chain: defaultChain, custom: map[string]Middleware{MiddlewareAuth: passthrough}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setChain(t, tt.chain, tt.custom)
+			ctx := auth.WithIdentity(context.Background(), &model.Identity{Name: "tester", Method: "api-key", Profile: "codec"})
+			for _, item := range McpHandler.GetList() {
+				if item.Name != "Base64Encode" && item.Name != "Md5Encode" {
+					continue
+				}
+				result, err := callTool(McpHandler.GetMcpFn(&item), ctx, item.Name, map[string]any{"text": "hello"})
+				if err != nil {
+					t.Fatalf("%s: %v", item.Name, err)
+				}
+				denied := result.IsError && strings.HasPrefix(result.Content[0].(mcp.TextContent).Text, ErrCodePermissionDenied)
+				if want := item.Name == "Md5Encode"; denied != want {
+					t.Errorf("%s: denied = %t, want %t", item.Name, denied, want)
+				}
+			}
+		})
+	}
+}
+
+// 工具描述列出自身的错误码与中间件链可能返回的通用错误码，各错误码只出现一次
+func TestToolDescriptionErrorCodes(t *testing.T) {
+	for _, item := range McpHandler.GetList() {
+		description := withErrorCodes(item.Description, item.ErrorCodes)
+		_, list, _ := strings.Cut(description, "codes: ")
+		codes := strings.Split(strings.TrimSuffix(list, "."), ", ")
+		for _, code := range append(slices.Clone(item.ErrorCodes), commonErrorCodes...) {
+			if n := len(slices.DeleteFunc(slices.Clone(codes), func(c string) bool { return c != code })); n != 1 {
+				t.Errorf("%s 的描述中 %s 出现 %d 次：%s", item.Name, code, n, description)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"ai-mcp/internal/consts"
+	"context"
+	"sync"
+	"time"
+)
+
+var (
+	// lifecycleCtx 在优雅关闭超时后取消，用于终止仍在执行的工具调用
+	lifecycleCtx, lifecycleCancel = context.WithCancel(context.Background())
+	inflightMu                    sync.Mutex
+	inflight                      sync.WaitGroup
+	draining                      bool
+)
+
+// begin 登记一次工具调用，返回的上下文会在关闭超时后被取消；服务关闭中时 ok 为 false
+func (s *sMcpHandler) begin(ctx context.Context) (toolCtx context.Context, done func(), ok bool) {
+	inflightMu.Lock()
+	defer inflightMu.Unlock()
+	if draining {
+		return ctx, nil, false
+	}
+	inflight.Add(1)
+	toolCtx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(lifecycleCtx, cancel)
+	done = func() {
+		stop()
+		cancel()
+		inflight.Done()
+	}
+	return toolCtx, done, true
+}
+
+// Drain 拒绝新的工具调用并等待进行中的调用结束，超过 timeout 后取消其上下文（同时终止 shell 子进程）
+func (s *sMcpHandler) Drain(timeout time.Duration) (drained bool) {
+	inflightMu.Lock()
+	draining = true
+	inflightMu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		inflight.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return true
+	case <-time.After(timeout):
+		consts.Logger.Warningf(consts.Ctx, "等待进行中的工具调用超时（%s），强制取消", timeout)
+		lifecycleCancel()
+	}
+
+	// 取消后留出短暂时间让工具返回并清理子进程
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		consts.Logger.Warning(consts.Ctx, "仍有工具调用未在取消后退出")
+	}
+	return false
+}
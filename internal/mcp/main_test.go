@@ -0,0 +1,44 @@
+package mcp
+
+import (
+	"ai-mcp/internal/consts"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// 沙箱内的测试进程通过该环境变量接收要连接的地址，用于检查网络隔离
+const sandboxDialEnv = "AI_MCP_SANDBOX_TEST_DIAL"
+
+// 网络探测连接失败时的退出码，与 panic 等其他失败区分
+const sandboxDialFailed = 3
+
+// TestMain 日志写入临时目录，避免测试在源码目录下生成 logs；
+// 测试程序同时充当沙箱初始化进程（runSandboxed 重新启动当前程序）与沙箱内的网络探测程序
+func TestMain(m *testing.M) {
+	if code, ok := McpTool.RunSandboxInit(); ok {
+		os.Exit(code)
+	}
+	if addr, ok := os.LookupEnv(sandboxDialEnv); ok {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err != nil {
+			os.Exit(sandboxDialFailed)
+		}
+		_ = conn.Close()
+		os.Exit(0)
+	}
+	os.Exit(runWithTempLogs(m))
+}
+
+func runWithTempLogs(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "ai-mcp-test-logs-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = consts.Logger.SetPath(dir); err != nil {
+		panic(err)
+	}
+	return m.Run()
+}
@@ -1,15 +1,15 @@
 package mcp
 
 import (
+	"ai-mcp/internal/model"
 	"context"
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/hex"
-	"errors"
+	"encoding/json"
 	"strings"
 
 	"github.com/gogf/gf/v2/encoding/gjson"
-	"github.com/gogf/gf/v2/frame/g"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
@@ -17,13 +17,13 @@ import (
 func (s *sMcpTool) Md5Encode(ctx context.Context, request mcp.CallToolRequest) (out *mcp.CallToolResult, err error) {
 	text := request.GetString("text", "")
 	if text == "" {
-		err = errors.New("text is required")
+		out = toolError(ErrCodeInvalidArgument, "text is required")
 		return
 	}
 	sum := md5.Sum([]byte(text))
-	out = mcp.NewToolResultText(gjson.MustEncodeString(g.Map{
-		"md5": hex.EncodeToString(sum[:]),
-	}))
+	out = mcp.NewToolResultStructuredOnly(model.Md5EncodeOutput{
+		Md5: hex.EncodeToString(sum[:]),
+	})
 	return
 }
 
@@ -31,13 +31,13 @@ func (s *sMcpTool) Md5Encode(ctx context.Context, request mcp.CallToolRequest) (
 func (s *sMcpTool) Base64Encode(ctx context.Context, request mcp.CallToolRequest) (out *mcp.CallToolResult, err error) {
 	text := request.GetString("text", "")
 	if text == "" {
-		err = errors.New("text is required")
+		out = toolError(ErrCodeInvalidArgument, "text is required")
 		return
 	}
 	encoded := base64.StdEncoding.EncodeToString([]byte(text))
-	out = mcp.NewToolResultText(gjson.MustEncodeString(g.Map{
-		"base64": encoded,
-	}))
+	out = mcp.NewToolResultStructuredOnly(model.Base64EncodeOutput{
+		Base64: encoded,
+	})
 	return
 }
 
@@ -45,20 +45,17 @@ func (s *sMcpTool) Base64Encode(ctx context.Context, request mcp.CallToolRequest
 func (s *sMcpTool) Base64Decode(ctx context.Context, request mcp.CallToolRequest) (out *mcp.CallToolResult, err error) {
 	data := request.GetString("data", "")
 	if data == "" {
-		err = errors.New("data is required")
+		out = toolError(ErrCodeInvalidArgument, "data is required")
 		return
 	}
 	decodedBytes, decErr := base64.StdEncoding.DecodeString(data)
 	if decErr != nil {
-		out = mcp.NewToolResultText(gjson.MustEncodeString(g.Map{
-			"error": "invalid base64",
-		}))
-		err = nil
+		out = toolError(ErrCodeInvalidArgument, "invalid base64: %s", decErr.Error())
 		return
 	}
-	out = mcp.NewToolResultText(gjson.MustEncodeString(g.Map{
-		"text": string(decodedBytes),
-	}))
+	out = mcp.NewToolResultStructuredOnly(model.Base64DecodeOutput{
+		Text: string(decodedBytes),
+	})
 	return
 }
 
@@ -66,16 +63,13 @@ func (s *sMcpTool) Base64Decode(ctx context.Context, request mcp.CallToolRequest
 func (s *sMcpTool) JwtParse(ctx context.Context, request mcp.CallToolRequest) (out *mcp.CallToolResult, err error) {
 	token := request.GetString("token", "")
 	if token == "" {
-		err = errors.New("token is required")
+		out = toolError(ErrCodeInvalidArgument, "token is required")
 		return
 	}
 
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
-		out = mcp.NewToolResultText(gjson.MustEncodeString(g.Map{
-			"error": "invalid jwt format",
-		}))
-		err = nil
+		out = toolError(ErrCodeInvalidArgument, "invalid jwt format")
 		return
 	}
 
@@ -83,26 +77,22 @@ func (s *sMcpTool) JwtParse(ctx context.Context, request mcp.CallToolRequest) (o
 	payloadBytes, errP := base64.RawURLEncoding.DecodeString(parts[1])
 	// 第三段是签名，保持原样
 	if errH != nil || errP != nil {
-		out = mcp.NewToolResultText(gjson.MustEncodeString(g.Map{
-			"error": "invalid jwt base64 segments",
-		}))
-		err = nil
+		out = toolError(ErrCodeInvalidArgument, "invalid jwt base64 segments")
 		return
 	}
 
-	// 解析为 JSON 对象（如果是 JSON）
-	headerJson, _ := gjson.DecodeToJson(headerBytes)
-	payloadJson, _ := gjson.DecodeToJson(payloadBytes)
+	// 解析为 JSON 对象（如果是 JSON），失败时对应字段省略
+	var headerJson, payloadJson map[string]any
+	_ = json.Unmarshal(headerBytes, &headerJson)
+	_ = json.Unmarshal(payloadBytes, &payloadJson)
 
-	result := g.Map{
-		"rawHeader":    parts[0],
-		"rawPayload":   parts[1],
-		"rawSignature": parts[2],
-		"header":       headerJson,  // 可能为 nil
-		"payload":      payloadJson, // 可能为 nil
-	}
-
-	out = mcp.NewToolResultText(gjson.MustEncodeString(result))
+	out = mcp.NewToolResultStructuredOnly(model.JwtParseOutput{
+		RawHeader:    parts[0],
+		RawPayload:   parts[1],
+		RawSignature: parts[2],
+		Header:       headerJson,
+		Payload:      payloadJson,
+	})
 	return
 }
 
@@ -111,20 +101,17 @@ func (s *sMcpTool) JsonEncode(ctx context.Context, request mcp.CallToolRequest)
 	// 允许两种入参形式：raw 任意字符串或 obj JSON 对象（调用方通常传字符串更通用）
 	raw := request.GetString("raw", "")
 	if raw == "" {
-		err = errors.New("raw is required")
+		out = toolError(ErrCodeInvalidArgument, "raw is required")
 		return
 	}
 	j, jErr := gjson.LoadContent([]byte(raw))
 	if jErr != nil {
-		out = mcp.NewToolResultText(gjson.MustEncodeString(g.Map{
-			"error": "invalid json",
-		}))
-		err = nil
+		out = toolError(ErrCodeInvalidArgument, "invalid json: %s", jErr.Error())
 		return
 	}
 	// 紧凑输出；如需 pretty，可扩展参数
-	out = mcp.NewToolResultText(gjson.MustEncodeString(g.Map{
-		"json": j.MustToJsonString(),
-	}))
+	out = mcp.NewToolResultStructuredOnly(model.JsonEncodeOutput{
+		Json: j.MustToJsonString(),
+	})
 	return
 }
@@ -1,14 +1,16 @@
 package mcp
 
 import (
+	"ai-mcp/internal/auth"
 	"ai-mcp/internal/consts"
+	"ai-mcp/internal/model"
 	"ai-mcp/utility"
 	"context"
-	"errors"
+	"database/sql"
 	"fmt"
 	"strings"
 
-	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/database/gdb"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -17,36 +19,101 @@ import (
 func (s *sMcpTool) ExecSql(ctx context.Context, request mcp.CallToolRequest) (out *mcp.CallToolResult, err error) {
 	sql := request.GetString("sql", "")
 	if sql == "" {
-		err = errors.New("sql is required")
+		out = toolError(ErrCodeInvalidArgument, "sql is required")
 		return
 	}
 
-	// 检查是否启用只读模式
-	if consts.Config.DbConfig != nil && consts.Config.DbConfig.Readonly {
+	// 检查是否启用只读模式（全局配置或调用方授权配置）
+	readonly := consts.Config.DbConfig != nil && consts.Config.DbConfig.Readonly
+	if profile := auth.Auth.Profile(ctx); profile != nil && profile.SqlReadonly {
+		readonly = true
+	}
+	if readonly {
 		if !isReadOnlySQL(sql) {
 			errMsg := "数据库当前处于只读模式，只允许执行查询操作（SELECT语句）"
 			consts.Logger.Warning(ctx, errMsg)
-			out = mcp.NewToolResultText(errMsg)
-			err = nil
+			out = toolError(ErrCodeReadonlyViolation, "%s", errMsg)
 			return
 		}
 	}
 
-	sqlOut, err := g.DB().Query(ctx, sql)
+	// 通过包装的连接执行，在 gdb 转换结果前记录列信息；空结果同样可以返回列
+	var (
+		db     = g.DB()
+		link   = &columnLink{}
+		sqlOut gdb.Result
+	)
+	if readonly {
+		sqlOut, err = queryReadOnly(ctx, db, link, sql)
+	} else if link.Link, err = db.GetCore().SlaveLink(); err == nil {
+		sqlOut, err = db.DoQuery(ctx, link, sql)
+	}
 	if err != nil {
-		outStr := fmt.Sprintf("数据库执行失败：%s", err.Error())
-		consts.Logger.Error(ctx, outStr)
-		out = mcp.NewToolResultText(outStr)
+		consts.Logger.Errorf(ctx, "数据库执行失败：%s", err.Error())
+		out = toolError(ErrCodeDbQueryFailed, "数据库执行失败：%s", err.Error())
 		err = nil
 		return
 	}
 
-	respStr, err := utility.ConvertAnyToMarkdownTable(sqlOut.List())
+	// 文本内容保持 Markdown 表格，结构化内容返回带类型的行与列信息
+	rows := sqlOut.List()
+	respStr, tableErr := utility.ConvertAnyToMarkdownTable(rows)
+	if tableErr != nil {
+		respStr = tableErr.Error()
+	}
+	out = mcp.NewToolResultStructured(model.SqlOutput{
+		Columns:  sqlColumns(link.columns),
+		Rows:     rows,
+		RowCount: len(rows),
+	}, respStr)
+	return
+}
+
+// queryReadOnly 在只读事务中执行语句，结束后始终回滚。关键字检查之外由数据库拒绝写操作，
+// 驱动不支持只读事务时返回错误而不是退回普通连接
+func queryReadOnly(ctx context.Context, db gdb.DB, link *columnLink, sql string) (result gdb.Result, err error) {
+	tx, err := db.BeginWithOptions(ctx, gdb.TxOptions{ReadOnly: true})
 	if err != nil {
 		return
 	}
-	out = mcp.NewToolResultText(respStr)
-	return
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	link.Link = txLink{tx.GetSqlTX()}
+	return db.DoQuery(ctx, link, sql)
+}
+
+// txLink 与 gdb 内部的 txLink 相同，使用事务作为 gdb.Link
+type txLink struct{ *sql.Tx }
+
+func (txLink) IsOnMaster() bool    { return true }
+func (txLink) IsTransaction() bool { return true }
+
+// columnLink 记录查询结果的列信息，gdb 将 sql.Rows 转换为 Result 时会丢失列顺序与类型
+type columnLink struct {
+	gdb.Link
+	columns []*sql.ColumnType
+}
+
+func (l *columnLink) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := l.Link.QueryContext(ctx, query, args...)
+	if err == nil {
+		l.columns, _ = rows.ColumnTypes()
+	}
+	return rows, err
+}
+
+// sqlColumns 按查询中的顺序返回结果列，类型与是否可为 NULL 取自数据库驱动
+func sqlColumns(columnTypes []*sql.ColumnType) []model.SqlColumn {
+	columns := make([]model.SqlColumn, 0, len(columnTypes))
+	for _, columnType := range columnTypes {
+		column := model.SqlColumn{Name: columnType.Name(), Type: columnType.DatabaseTypeName()}
+		if nullable, ok := columnType.Nullable(); ok {
+			column.Nullable = &nullable
+		}
+		columns = append(columns, column)
+	}
+	return columns
 }
 
 // GetDatabaseInfo 获取数据库信息
@@ -56,21 +123,21 @@ func (s *sMcpTool) GetDatabaseInfo(ctx context.Context, request mcp.CallToolRequ
 	// 获取数据库配置信息
 	dbConfig := g.DB(dbname).GetConfig()
 	if dbConfig == nil {
-		err = errors.New("无法获取数据库配置")
+		out = toolError(ErrCodeDbUnavailable, "无法获取数据库配置")
 		return
 	}
 
 	// 构建数据库信息
-	dbInfo := g.Map{
-		"databaseType": dbConfig.Type,
-		"host":         extractHostFromLink(dbConfig.Link),
-		"port":         extractPortFromLink(dbConfig.Link),
-		"databaseName": extractDatabaseNameFromLink(dbConfig.Link),
-		"username":     extractUsernameFromLink(dbConfig.Link),
-		"prefix":       dbConfig.Prefix,
-		"createdAt":    dbConfig.CreatedAt,
-		"updatedAt":    dbConfig.UpdatedAt,
-		"debug":        dbConfig.Debug,
+	dbInfo := model.DatabaseInfoOutput{
+		DatabaseType: dbConfig.Type,
+		Host:         extractHostFromLink(dbConfig.Link),
+		Port:         extractPortFromLink(dbConfig.Link),
+		DatabaseName: extractDatabaseNameFromLink(dbConfig.Link),
+		Username:     extractUsernameFromLink(dbConfig.Link),
+		Prefix:       dbConfig.Prefix,
+		CreatedAt:    dbConfig.CreatedAt,
+		UpdatedAt:    dbConfig.UpdatedAt,
+		Debug:        dbConfig.Debug,
 	}
 
 	// 测试连接并获取数据库版本信息
@@ -78,8 +145,7 @@ func (s *sMcpTool) GetDatabaseInfo(ctx context.Context, request mcp.CallToolRequ
 	if versionQuery != "" {
 		sqlOut, queryErr := g.DB().Query(ctx, versionQuery)
 		if queryErr == nil && sqlOut != nil && len(sqlOut.List()) > 0 {
-			versionInfo := sqlOut.List()[0]
-			dbInfo["version"] = versionInfo
+			dbInfo.Version = sqlOut.List()[0]
 		}
 	}
 
@@ -88,12 +154,11 @@ func (s *sMcpTool) GetDatabaseInfo(ctx context.Context, request mcp.CallToolRequ
 	if sizeQuery != "" {
 		sqlOut, queryErr := g.DB().Query(ctx, sizeQuery)
 		if queryErr == nil && sqlOut != nil && len(sqlOut.List()) > 0 {
-			sizeInfo := sqlOut.List()[0]
-			dbInfo["databaseSize"] = sizeInfo
+			dbInfo.DatabaseSize = sqlOut.List()[0]
 		}
 	}
 
-	out = mcp.NewToolResultText(gjson.MustEncodeString(dbInfo))
+	out = mcp.NewToolResultStructuredOnly(dbInfo)
 	return
 }
 
@@ -224,36 +289,150 @@ func getSizeQuery(dbType, dbname string) string {
 	}
 }
 
-// isReadOnlySQL 检查SQL语句是否为只读操作
-func isReadOnlySQL(sql string) bool {
-	// 去除前后空格并转换为大写
-	sql = strings.TrimSpace(strings.ToUpper(sql))
+// 只读语句允许的首个关键字
+var readOnlySqlPrefixes = map[string]bool{
+	"SELECT": true, "WITH": true, "SHOW": true, "DESCRIBE": true, "DESC": true, "EXPLAIN": true, "PRAGMA": true,
+}
 
-	// 检查是否以SELECT开头（包括WITH语句，因为WITH通常用于查询）
-	if strings.HasPrefix(sql, "SELECT") || strings.HasPrefix(sql, "WITH") {
-		return true
-	}
+// 出现在只读语句任意位置即拒绝的关键字：WITH 之后的写语句、SELECT ... INTO OUTFILE/@var、
+// 加锁读（FOR UPDATE、LOCK IN SHARE MODE）以及存储过程调用等
+var readOnlySqlForbidden = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "REPLACE": true, "MERGE": true, "UPSERT": true,
+	"CREATE": true, "DROP": true, "ALTER": true, "TRUNCATE": true, "RENAME": true, "GRANT": true, "REVOKE": true,
+	"CALL": true, "EXEC": true, "EXECUTE": true, "LOAD": true, "HANDLER": true, "LOCK": true, "UNLOCK": true,
+	"INTO": true, "OUTFILE": true, "DUMPFILE": true,
+}
 
-	// 检查是否为SHOW语句（MySQL的SHOW语句是只读的）
-	if strings.HasPrefix(sql, "SHOW") {
-		return true
+// 同名的字符串函数（如 REPLACE(s, a, b)、MySQL 的 INSERT(s, pos, len, t)）后面紧跟括号，不视为写语句
+var readOnlySqlFunctions = map[string]bool{"REPLACE": true, "INSERT": true}
+
+// isReadOnlySQL 检查SQL语句是否为只读操作：单条语句，首个关键字为查询类语句，且不包含写入、导出或加锁的关键字。
+// 字符串、带引号的标识符与注释中的内容不参与判断，MySQL 会执行的 /*! ... */ 注释按语句内容检查
+func isReadOnlySQL(sql string) bool {
+	tokens, ok := sqlTokens(sql)
+	if !ok || len(tokens) == 0 || !readOnlySqlPrefixes[tokens[0].word] {
+		return false
 	}
+	for i, token := range tokens {
+		if readOnlySqlForbidden[token.word] && !(readOnlySqlFunctions[token.word] && token.call) {
+			return false
+		}
+		// FOR SHARE、FOR KEY SHARE 加锁读
+		if token.word == "SHARE" && i > 0 && (tokens[i-1].word == "FOR" || tokens[i-1].word == "KEY") {
+			return false
+		}
+		// SQLite 的 PRAGMA name = value、PRAGMA name(value) 会修改设置
+		if tokens[0].word == "PRAGMA" && (token.word == "=" || token.call) {
+			return false
+		}
+	}
+	return true
+}
 
-	// 检查是否为DESCRIBE或DESC语句
-	if strings.HasPrefix(sql, "DESCRIBE") || strings.HasPrefix(sql, "DESC") {
-		return true
+// sqlToken 语句中的关键字或标识符（转为大写），call 表示其后紧跟左括号；= 单独作为一个 token
+type sqlToken struct {
+	word string
+	call bool
+}
+
+// sqlTokens 按 MySQL 的词法跳过字符串、带引号的标识符与注释后切分语句，包含多条语句或无法确定边界时 ok 为 false
+func sqlTokens(sql string) (tokens []sqlToken, ok bool) {
+	sql = strings.ToUpper(sql)
+	ended := false
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '-' && strings.HasPrefix(sql[i:], "--") && (i+2 == len(sql) || sql[i+2] <= ' '), c == '#':
+			// MySQL 中 -- 之后必须是空白才是注释，1--1 是减法
+			i = skipLine(sql, i)
+			continue
+		case strings.HasPrefix(sql[i:], "/*!"), strings.HasPrefix(sql[i:], "/*+"):
+			// MySQL 的可执行注释与优化器提示：跳过标记与版本号，内容按语句检查
+			i += 3
+			for i < len(sql) && sql[i] >= '0' && sql[i] <= '9' {
+				i++
+			}
+			continue
+		case strings.HasPrefix(sql[i:], "*/"):
+			i += 2
+			continue
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return nil, false
+			}
+			i += end + 4
+			continue
+		}
+		if ended {
+			// 分号之后还有内容，说明是多条语句
+			return nil, false
+		}
+		switch {
+		case c == ';':
+			ended = true
+			i++
+		case c == '\'' || c == '"' || c == '`':
+			end := skipQuoted(sql, i)
+			if end < 0 {
+				return nil, false
+			}
+			i = end
+		case c == '=':
+			tokens = append(tokens, sqlToken{word: "="})
+			i++
+		case isSqlWordChar(c):
+			start := i
+			for i < len(sql) && isSqlWordChar(sql[i]) {
+				i++
+			}
+			token := sqlToken{word: sql[start:i]}
+			next := i
+			for next < len(sql) && (sql[next] == ' ' || sql[next] == '\t' || sql[next] == '\n' || sql[next] == '\r') {
+				next++
+			}
+			token.call = next < len(sql) && sql[next] == '('
+			tokens = append(tokens, token)
+		default:
+			i++
+		}
 	}
+	return tokens, true
+}
 
-	// 检查是否为EXPLAIN语句
-	if strings.HasPrefix(sql, "EXPLAIN") {
-		return true
+// skipLine 跳过到行尾的注释
+func skipLine(sql string, i int) int {
+	if end := strings.IndexByte(sql[i:], '\n'); end >= 0 {
+		return i + end + 1
 	}
+	return len(sql)
+}
 
-	// 检查是否为PRAGMA语句（SQLite的PRAGMA语句通常是只读的）
-	if strings.HasPrefix(sql, "PRAGMA") {
-		return true
+// skipQuoted 跳过以 sql[i] 开始的字符串或带引号的标识符，支持反斜杠转义与连续两个引号，未闭合时返回 -1。
+// 开启 NO_BACKSLASH_ESCAPES 时 \' 会结束字符串，两种解释不一致，同样返回 -1
+func skipQuoted(sql string, i int) int {
+	quote := sql[i]
+	for i++; i < len(sql); i++ {
+		switch {
+		case sql[i] == '\\' && quote != '`':
+			if i+1 < len(sql) && sql[i+1] == quote {
+				return -1
+			}
+			i++
+		case sql[i] == quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
 	}
+	return -1
+}
 
-	// 其他情况都认为是非只读操作
-	return false
+func isSqlWordChar(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$' || c == '@' || c >= 0x80
 }
@@ -0,0 +1,153 @@
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+)
+
+// columnsDriver 测试用的 database/sql 驱动，所有查询返回固定的列定义与行
+type columnsDriver struct{}
+
+type columnsConn struct{}
+
+type columnsStmt struct{ query string }
+
+type columnsRows struct {
+	rows [][]driver.Value
+}
+
+var testColumns = []struct {
+	name, dbType string
+	nullable     bool
+}{
+	{"zeta", "BIGINT", false},
+	{"alpha", "VARCHAR", true},
+	{"created_at", "DATETIME", true},
+}
+
+func (columnsDriver) Open(string) (driver.Conn, error) { return columnsConn{}, nil }
+
+func (columnsConn) Prepare(query string) (driver.Stmt, error) { return columnsStmt{query}, nil }
+func (columnsConn) Close() error                              { return nil }
+func (columnsConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+func (columnsStmt) Close() error                               { return nil }
+func (columnsStmt) NumInput() int                              { return -1 }
+func (columnsStmt) Exec([]driver.Value) (driver.Result, error) { return driver.ResultNoRows, nil }
+func (s columnsStmt) Query([]driver.Value) (driver.Rows, error) {
+	if s.query == "empty" {
+		return &columnsRows{}, nil
+	}
+	return &columnsRows{rows: [][]driver.Value{{int64(1), nil, nil}}}, nil
+}
+
+func (r *columnsRows) Columns() []string {
+	names := make([]string, len(testColumns))
+	for i, column := range testColumns {
+		names[i] = column.name
+	}
+	return names
+}
+func (r *columnsRows) Close() error { return nil }
+func (r *columnsRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+func (r *columnsRows) ColumnTypeDatabaseTypeName(i int) string { return testColumns[i].dbType }
+func (r *columnsRows) ColumnTypeNullable(i int) (bool, bool)   { return testColumns[i].nullable, true }
+
+// sqlDbLink 与 gdb 内部的 dbLink 相同，直接使用 *sql.DB 作为 gdb.Link
+type sqlDbLink struct{ *sql.DB }
+
+func (sqlDbLink) IsOnMaster() bool    { return true }
+func (sqlDbLink) IsTransaction() bool { return false }
+
+func init() {
+	sql.Register("ai-mcp-columns", columnsDriver{})
+}
+
+func TestColumnLink(t *testing.T) {
+	db, err := sql.Open("ai-mcp-columns", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, query := range []string{"rows", "empty"} {
+		t.Run(query, func(t *testing.T) {
+			link := &columnLink{Link: sqlDbLink{db}}
+			rows, err := link.QueryContext(context.Background(), query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for rows.Next() {
+			}
+			_ = rows.Close()
+
+			// 列按查询顺序返回，类型与可空性来自驱动，空结果同样有列
+			columns := sqlColumns(link.columns)
+			if len(columns) != len(testColumns) {
+				t.Fatalf("columns = %+v", columns)
+			}
+			for i, want := range testColumns {
+				got := columns[i]
+				if got.Name != want.name || got.Type != want.dbType || got.Nullable == nil || *got.Nullable != want.nullable {
+					t.Errorf("column %d = {%s %s %v}, want %+v", i, got.Name, got.Type, got.Nullable, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIsReadOnlySQL(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{"SELECT * FROM user", true},
+		{"  select id from user where name = 'update' ", true},
+		{"SELECT REPLACE(name, 'a', 'b'), INSERT(name, 1, 2, 'x') FROM user", true},
+		{"SELECT `update`, \"delete\" FROM t -- into outfile\n", true},
+		{"SELECT 1 /* DELETE FROM t */", true},
+		{"SELECT 1 # UPDATE t", true},
+		{"SELECT 'it''s'", true},
+		{"SELECT 1;", true},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", true},
+		{"SHOW TABLES", true},
+		{"SHOW CHARACTER SET", true},
+		{"DESC user", true},
+		{"EXPLAIN SELECT 1", true},
+		{"PRAGMA table_info", true},
+		{"WITH x AS (SELECT 1) UPDATE t SET a = 1", false},
+		{"WITH x AS (SELECT 1) DELETE FROM t", false},
+		{"with x as (select 1) insert into t select * from x", false},
+		{"SELECT * FROM t INTO OUTFILE '/tmp/x'", false},
+		{"SELECT * INTO DUMPFILE '/tmp/x' FROM t", false},
+		{"SELECT 1 INTO @a", false},
+		{"SELECT * FROM t FOR UPDATE", false},
+		{"SELECT * FROM t FOR SHARE", false},
+		{"SELECT * FROM t LOCK IN SHARE MODE", false},
+		{"SELECT 1; DROP TABLE t", false},
+		{"SELECT 1 /*!50000 INTO OUTFILE '/tmp/x' */", false}, // MySQL 会执行 /*! */ 中的内容
+		{"SELECT 1--1 INTO OUTFILE '/tmp/x'", false},          // -- 后没有空白时不是注释
+		{"SELECT '\\' INTO OUTFILE '/tmp/x' -- '", false},     // NO_BACKSLASH_ESCAPES 下 \' 结束字符串
+		{"SELECT 'unterminated", false},
+		{"PRAGMA user_version = 1", false},
+		{"PRAGMA journal_mode(WAL)", false},
+		{"UPDATE t SET a = 1", false},
+		{"CALL p()", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isReadOnlySQL(tt.sql); got != tt.want {
+			t.Errorf("isReadOnlySQL(%q) = %t, want %t", tt.sql, got, tt.want)
+		}
+	}
+}
@@ -2,8 +2,8 @@ package mcp
 
 import (
 	"ai-mcp/internal/consts"
+	"ai-mcp/internal/model"
 	"context"
-	"errors"
 	"fmt"
 	"strings"
 
@@ -12,40 +12,59 @@ import (
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
+// redisCommands ExecRedisCommand 支持的 Redis 命令
+var redisCommands = []string{
+	// 键
+	"DEL", "UNLINK", "EXISTS", "EXPIRE", "EXPIREAT", "PEXPIRE", "PEXPIREAT", "PERSIST", "TTL", "PTTL", "EXPIRETIME",
+	"TYPE", "KEYS", "SCAN", "RENAME", "RENAMENX", "COPY", "TOUCH", "DUMP", "RESTORE", "OBJECT", "RANDOMKEY", "MOVE",
+	// 字符串
+	"GET", "SET", "SETNX", "SETEX", "PSETEX", "GETSET", "GETDEL", "GETEX", "GETRANGE", "SETRANGE", "MGET", "MSET", "MSETNX",
+	"APPEND", "STRLEN", "INCR", "INCRBY", "INCRBYFLOAT", "DECR", "DECRBY",
+	// 哈希
+	"HGET", "HSET", "HSETNX", "HMGET", "HMSET", "HDEL", "HEXISTS", "HGETALL", "HKEYS", "HVALS", "HLEN", "HSTRLEN",
+	"HINCRBY", "HINCRBYFLOAT", "HSCAN", "HRANDFIELD",
+	// 列表
+	"LPUSH", "RPUSH", "LPUSHX", "RPUSHX", "LPOP", "RPOP", "LLEN", "LRANGE", "LINDEX", "LSET", "LINSERT", "LREM", "LTRIM",
+	"LPOS", "LMOVE", "RPOPLPUSH",
+	// 集合
+	"SADD", "SREM", "SMEMBERS", "SISMEMBER", "SMISMEMBER", "SCARD", "SPOP", "SRANDMEMBER", "SMOVE", "SSCAN",
+	"SINTER", "SINTERCARD", "SINTERSTORE", "SUNION", "SUNIONSTORE", "SDIFF", "SDIFFSTORE",
+	// 有序集合
+	"ZADD", "ZREM", "ZCARD", "ZCOUNT", "ZSCORE", "ZMSCORE", "ZINCRBY", "ZRANK", "ZREVRANK", "ZRANGE", "ZREVRANGE",
+	"ZRANGEBYSCORE", "ZREVRANGEBYSCORE", "ZRANGEBYLEX", "ZLEXCOUNT", "ZPOPMIN", "ZPOPMAX", "ZSCAN",
+	"ZREMRANGEBYRANK", "ZREMRANGEBYSCORE", "ZREMRANGEBYLEX", "ZUNIONSTORE", "ZINTERSTORE", "ZRANDMEMBER",
+	// 流
+	"XADD", "XLEN", "XRANGE", "XREVRANGE", "XREAD", "XDEL", "XTRIM", "XINFO", "XPENDING", "XACK", "XGROUP",
+	// 位图、HyperLogLog 与地理位置
+	"GETBIT", "SETBIT", "BITCOUNT", "BITPOS", "BITFIELD", "PFADD", "PFCOUNT", "PFMERGE",
+	"GEOADD", "GEOPOS", "GEODIST", "GEOHASH", "GEOSEARCH",
+	// 发布订阅、脚本与服务器
+	"PUBLISH", "PUBSUB", "EVAL", "EVALSHA", "EVAL_RO", "EVALSHA_RO", "SCRIPT",
+	"PING", "ECHO", "INFO", "DBSIZE", "TIME", "MEMORY", "SLOWLOG", "CLIENT", "CONFIG", "ACL", "AUTH", "HELLO",
+	"LATENCY", "COMMAND", "LASTSAVE", "ROLE", "LOLWUT",
+}
+
 // ExecRedisCommand 执行Redis命令
 func (s *sMcpTool) ExecRedisCommand(ctx context.Context, request mcp.CallToolRequest) (out *mcp.CallToolResult, err error) {
 	// 获取命令和参数
 	command := request.GetString("command", "")
 	if command == "" {
-		err = errors.New("command is required")
+		out = toolError(ErrCodeInvalidArgument, "command is required")
 		return
 	}
 
-	// 获取命令参数
-	argsStr := request.GetString("args", "")
-	var args []interface{}
-	if argsStr != "" {
-		var argsArray []string
-		if err = gjson.Unmarshal([]byte(argsStr), &argsArray); err != nil {
-			errMsg := fmt.Sprintf("参数解析失败: %s", err.Error())
-			consts.Logger.Error(ctx, errMsg)
-			out = mcp.NewToolResultText(errMsg)
-			err = nil
-			return
-		}
-		// 转换为 interface{} 切片
-		args = make([]interface{}, len(argsArray))
-		for i, v := range argsArray {
-			args[i] = v
-		}
+	// 获取命令参数（旧客户端传入的 JSON 数组字符串已在参数校验时转换为数组）
+	argsArray := request.GetStringSlice("args", nil)
+	args := make([]interface{}, len(argsArray))
+	for i, v := range argsArray {
+		args[i] = v
 	}
 
 	// 获取 Redis 连接
 	conn, err := g.Redis().Conn(ctx)
 	if err != nil {
-		errMsg := fmt.Sprintf("Redis连接失败: %s", err.Error())
-		consts.Logger.Error(ctx, errMsg)
-		out = mcp.NewToolResultText(errMsg)
+		consts.Logger.Errorf(ctx, "Redis连接失败: %s", err.Error())
+		out = toolError(ErrCodeRedisUnavailable, "Redis连接失败: %s", err.Error())
 		err = nil
 		return
 	}
@@ -54,19 +73,67 @@ func (s *sMcpTool) ExecRedisCommand(ctx context.Context, request mcp.CallToolReq
 	// 执行 Redis 命令
 	result, err := conn.Do(ctx, command, args...)
 	if err != nil {
-		errMsg := fmt.Sprintf("Redis命令执行失败: %s", err.Error())
-		consts.Logger.Error(ctx, errMsg)
-		out = mcp.NewToolResultText(errMsg)
+		consts.Logger.Errorf(ctx, "Redis命令执行失败: %s", err.Error())
+		out = toolError(ErrCodeRedisCommandFailed, "Redis命令执行失败: %s", err.Error())
 		err = nil
 		return
 	}
 
-	// 格式化返回结果
-	resultStr := formatRedisResult(command, result)
-	out = mcp.NewToolResultText(resultStr)
+	// 文本内容保持原有格式，结构化内容保留回复的类型与嵌套结构
+	value := redisValue(result.Val())
+	out = mcp.NewToolResultStructured(model.RedisOutput{
+		Command: strings.ToUpper(command),
+		Type:    redisValueType(value),
+		Value:   value,
+	}, formatRedisResult(command, result))
 	return
 }
 
+// redisValue 将 Redis 回复中的字节数组转换为字符串，便于 JSON 输出
+func redisValue(value any) any {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = redisValue(item)
+		}
+		return list
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(redisValue(key))] = redisValue(item)
+		}
+		return m
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for key, item := range v {
+			m[key] = redisValue(item)
+		}
+		return m
+	default:
+		return v
+	}
+}
+
+func redisValueType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return "string"
+	case int64:
+		return "integer"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "map"
+	default:
+		return "other"
+	}
+}
+
 // formatRedisResult 格式化Redis命令的返回结果
 func formatRedisResult(command string, result interface{}) string {
 	if result == nil {
@@ -1,28 +1,33 @@
 package mcp
 
 import (
+	"ai-mcp/internal/metrics"
+	"ai-mcp/internal/tracing"
 	"context"
 	"errors"
+	"fmt"
+	"io/fs"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gogf/gf/v2/encoding/gjson"
 	"github.com/gogf/gf/v2/frame/g"
-	"github.com/gogf/gf/v2/util/gconv"
 	"github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // RunSafeShellCommand 执行安全受限的终端命令
 func (s *sMcpTool) RunSafeShellCommand(ctx context.Context, request mcp.CallToolRequest) (out *mcp.CallToolResult, err error) {
 	command := request.GetString("command", "")
 	if command == "" {
-		err = errors.New("command is required")
+		out = toolError(ErrCodeInvalidArgument, "command is required")
 		return
 	}
 
 	// 超时（秒），默认 10 秒，最大 60 秒
-	timeoutSeconds := gconv.Int(request.GetString("timeoutSeconds", "10"))
+	timeoutSeconds := request.GetInt("timeoutSeconds", 10)
 	if timeoutSeconds <= 0 {
 		timeoutSeconds = 10
 	}
@@ -30,38 +35,60 @@ func (s *sMcpTool) RunSafeShellCommand(ctx context.Context, request mcp.CallTool
 		timeoutSeconds = 60
 	}
 
-	// 可选工作目录
-	cwd := request.GetString("cwd", "")
+	// 工作目录，为空时使用默认目录，需位于 shell.roots 之内
+	p := getShellPolicy()
+	cwd, err := p.resolveWorkDir(request.GetString("cwd", ""))
+	if err != nil {
+		code := ErrCodeCommandRejected
+		if errors.Is(err, fs.ErrNotExist) {
+			code = ErrCodeInvalidArgument
+		}
+		out = toolError(code, "%s", err.Error())
+		err = nil
+		return
+	}
 
 	// 风险校验
-	if err = validateSafeCommand(command); err != nil {
-		out = mcp.NewToolResultText(err.Error())
+	pipeline, err := validateSafeCommand(command, cwd)
+	if err != nil {
+		out = toolError(ErrCodeCommandRejected, "%s", err.Error())
 		err = nil
 		return
 	}
 
-	// 为了避免交互，使用非交互 shell，并由我们禁用危险操作符
+	// 沙箱不可用时拒绝执行，而不是退回到无隔离执行
+	sandbox := p.sandboxFor(ctx)
+	if sandbox != nil && !sandboxSupported {
+		out = toolError(ErrCodeCommandRejected, "当前系统不支持沙箱")
+		return
+	}
+
 	ctxTimeout, cancel := context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
 	defer cancel()
 
-	// 使用 /bin/zsh -lc 或 /bin/sh -lc 均可；macOS 默认有 zsh
-	// 我们已在 validateSafeCommand 中禁止了管道与重定向等操作符
-	cmd := exec.CommandContext(ctxTimeout, "/bin/zsh", "-lc", command)
-	if cwd != "" {
-		cmd.Dir = cwd
-	}
-
-	stdoutBytes := &strings.Builder{}
-	stderrBytes := &strings.Builder{}
-	cmd.Stdout = stdoutBytes
-	cmd.Stderr = stderrBytes
+	stdoutBytes := &outputBuffer{}
+	stderrBytes := &outputBuffer{}
 
+	_, span := tracing.Start(ctxTimeout, "exec.Command",
+		attribute.String("shell.command", command),
+		attribute.String("shell.cwd", cwd),
+		attribute.String("shell.exec", p.exec),
+		attribute.Bool("shell.sandbox", sandbox != nil),
+		attribute.Int("shell.timeout_seconds", timeoutSeconds),
+	)
 	start := time.Now()
-	runErr := cmd.Run()
+	metrics.ShellProcesses.Inc()
+	var runErr error
+	if sandbox != nil {
+		runErr = runSandboxed(ctxTimeout, p.newSandboxSpec(sandbox, command, cwd, pipeline), stdoutBytes, stderrBytes)
+	} else if p.exec == ShellExecShell {
+		runErr = runShell(ctxTimeout, p.shell, command, cwd, stdoutBytes, stderrBytes)
+	} else {
+		runErr = runPipeline(ctxTimeout, pipeline, cwd, stdoutBytes, stderrBytes)
+	}
+	metrics.ShellProcesses.Dec()
 	durationMs := time.Since(start).Milliseconds()
 
-	killedByTimeout := ctxTimeout.Err() == context.DeadlineExceeded
-
 	// 退出码
 	exitCode := 0
 	if runErr != nil {
@@ -69,10 +96,23 @@ func (s *sMcpTool) RunSafeShellCommand(ctx context.Context, request mcp.CallTool
 		if exitErr, ok := runErr.(*exec.ExitError); ok {
 			exitCode = exitErr.ExitCode()
 		} else {
+			// 进程未能启动，没有 shell 输出错误信息，写入 stderr 便于排查
 			exitCode = -1
+			_, _ = stderrBytes.Write([]byte(runErr.Error()))
 		}
 	}
 
+	killedByTimeout := ctxTimeout.Err() == context.DeadlineExceeded
+	span.SetAttributes(
+		attribute.Int("shell.exit_code", exitCode),
+		attribute.Bool("shell.killed_by_timeout", killedByTimeout),
+	)
+	if runErr != nil {
+		tracing.End(span, "error", runErr)
+	} else {
+		tracing.End(span, "success", nil)
+	}
+
 	// 限制输出大小，防止过大返回
 	stdout := trimLong(stdoutBytes.String(), 64*1024)
 	stderr := trimLong(stderrBytes.String(), 32*1024)
@@ -84,7 +124,7 @@ func (s *sMcpTool) RunSafeShellCommand(ctx context.Context, request mcp.CallTool
 		"durationMs":       durationMs,
 		"killedByTimeout":  killedByTimeout,
 		"timeoutSeconds":   timeoutSeconds,
-		"workingDirectory": cmd.Dir,
+		"workingDirectory": cwd,
 		"command":          command,
 	}
 
@@ -93,43 +133,36 @@ func (s *sMcpTool) RunSafeShellCommand(ctx context.Context, request mcp.CallTool
 	return
 }
 
-// validateSafeCommand 黑名单规则与操作符禁用
-func validateSafeCommand(command string) error {
-	normalized := strings.ToLower(strings.TrimSpace(command))
-
-	// 禁用危险操作符与特性（避免复合执行、重定向、替换等）。放开 | 管道符。
-	bannedOperators := []string{
-		"||", "&&", ";", ">", ">>", "<", "<<", "`", "$(", "&", "2>", "2>>",
+// validateSafeCommand 解析命令语法树，只允许由 | 连接的简单命令，并按 shell.mode 使用白名单或旧版黑名单校验每段命令
+func validateSafeCommand(command, cwd string) (pipeline []shellStage, err error) {
+	p := getShellPolicy()
+	if pipeline, err = parseCommand(command, cwd, p.lang); err != nil {
+		return
 	}
-	for _, op := range bannedOperators {
-		if strings.Contains(normalized, op) {
-			return errors.New("命令包含被禁用的操作符: " + op)
-		}
+	if p.mode == ShellModeBlacklist {
+		err = validateBlacklist(command, pipeline, p.maxPipes)
+	} else {
+		err = p.validate(pipeline)
+	}
+	if err == nil {
+		err = p.checkPathArgs(cwd, pipeline)
 	}
+	return
+}
 
-	// 允许使用 |，对每个分段分别做首 token 校验
-	segments := strings.Split(normalized, "|")
-	if len(segments) > 1 {
-		// 最多允许 3 个管道（4 个分段）
-		if len(segments)-1 > 3 {
-			return errors.New("管道分段过多：最多允许 3 个管道")
-		}
-		for _, seg := range segments {
-			segTrim := strings.TrimSpace(seg)
-			if segTrim == "" {
-				return errors.New("无效的空管道分段")
-			}
-			if err := validateFirstToken(segTrim); err != nil {
-				return err
-			}
-		}
-	} else {
-		if err := validateFirstToken(normalized); err != nil {
+// validateBlacklist 旧版黑名单规则，shell.mode 为 blacklist 时使用
+func validateBlacklist(command string, pipeline []shellStage, maxPipes int) error {
+	if len(pipeline)-1 > maxPipes {
+		return fmt.Errorf("管道分段过多：最多允许 %d 个管道", maxPipes)
+	}
+	for _, stage := range pipeline {
+		if err := validateFirstToken(stage.argv[0]); err != nil {
 			return err
 		}
 	}
 
 	// 禁用高危命令片段
+	normalized := strings.ToLower(strings.TrimSpace(command))
 	bannedFragments := []string{
 		"rm -rf", ":(){:|:&};:", "mkfs.", "/dev/", "/etc/passwd",
 	}
@@ -142,8 +175,8 @@ func validateSafeCommand(command string) error {
 	return nil
 }
 
-func validateFirstToken(cmd string) error {
-	// 禁用高危命令（匹配首 token）
+func validateFirstToken(name string) error {
+	// 禁用高危命令（匹配首 token，按文件名比较以覆盖 /bin/rm 这类写法）
 	bannedCommands := []string{
 		"rm", "rmdir", "mkfs", "dd", "chmod", "chown", "mv", "shutdown", "reboot",
 		"halt", "poweroff", "init", "service", "systemctl", "mount", "umount", "kill",
@@ -152,7 +185,7 @@ func validateFirstToken(cmd string) error {
 	}
 
 	// 仅检查首 token，避免误杀比如 "echo rm"
-	firstToken := firstTokenOf(cmd)
+	firstToken := strings.ToLower(filepath.Base(name))
 	for _, b := range bannedCommands {
 		if firstToken == b {
 			return errors.New("命令被禁用: " + b)
@@ -161,14 +194,6 @@ func validateFirstToken(cmd string) error {
 	return nil
 }
 
-func firstTokenOf(s string) string {
-	fields := strings.Fields(s)
-	if len(fields) == 0 {
-		return ""
-	}
-	return fields[0]
-}
-
 func trimLong(s string, max int) string {
 	if len(s) <= max {
 		return s
@@ -0,0 +1,114 @@
+package mcp
+
+import (
+	"ai-mcp/internal/consts"
+	"ai-mcp/internal/model"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// setShellPolicy 以指定的 shell 配置替换全局策略，测试结束后恢复为按配置文件重新编译
+func setShellPolicy(t *testing.T, cfg *model.ShellConfig) {
+	t.Helper()
+	policyOnce.Do(func() {})
+	policy = newShellPolicy(cfg)
+	t.Cleanup(func() {
+		policyOnce, policy = sync.Once{}, nil
+	})
+}
+
+func TestValidateSafeCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		wantErr bool
+	}{
+		{name: "引号内的管道符", command: `grep 'a|b' README.md`},
+		{name: "引号内的重定向符", command: `echo ">"`},
+		{name: "管道", command: `ps aux | grep mcp | wc -l`},
+		{name: "2>&1", command: `ls -la 2>&1 | head -n 5`},
+		{name: "变量", command: `echo $HOME`, wantErr: true},
+		{name: "参数展开", command: `cat ${HOME}/.profile`, wantErr: true},
+		{name: "命令替换", command: `echo $(id)`, wantErr: true},
+		{name: "反引号", command: "echo `id`", wantErr: true},
+		{name: "算术展开", command: `echo $((1+1))`, wantErr: true},
+		{name: "花括号展开", command: `cat {a,b}`, wantErr: true},
+		{name: "$'...'", command: `cat $'.env'`, wantErr: true},
+		{name: "$\"...\"", command: `cat $".env"`, wantErr: true},
+		{name: "绝对路径 rm", command: `/bin/rm -rf /tmp/x`, wantErr: true},
+		{name: "find -delete", command: `find . -name '*.log' -delete`, wantErr: true},
+		{name: "引号包裹的 -delete", command: `find . '-delete'`, wantErr: true},
+		{name: "find -exec", command: `find . -exec cat {} ;`, wantErr: true},
+		{name: "xargs rm", command: `ls | xargs rm`, wantErr: true},
+		{name: "选项值紧跟选项", command: `date -f.env`, wantErr: true},
+		{name: "选项值带路径", command: `grep -f/etc/shadow x`, wantErr: true},
+		{name: "递归 grep", command: `grep -r hunter2 .`, wantErr: true},
+		{name: "sort 从标准输入读取文件名", command: `echo -ne '/tmp/\162vsecret/key\0' | sort --files0-from=-`, wantErr: true},
+		{name: "wc 从文件读取文件名", command: `wc --files0-from list.txt`, wantErr: true},
+		{name: "du 缩写的 --files0-from", command: `du --files0=-`, wantErr: true},
+		{name: "&&", command: `ls && id`, wantErr: true},
+		{name: ";", command: `ls; id`, wantErr: true},
+		{name: "输出重定向", command: `echo x > out.txt`, wantErr: true},
+		{name: "命令前赋值", command: `LANG=C ls`, wantErr: true},
+		{name: "不在白名单", command: `python -c 'print(1)'`, wantErr: true},
+	}
+	// direct 按 POSIX 语法、shell 按 bash 语法校验，两者结果应一致
+	for _, exec := range []string{ShellExecDirect, ShellExecShell} {
+		setShellPolicy(t, &model.ShellConfig{Exec: exec})
+		cwd := t.TempDir()
+		for _, tt := range tests {
+			t.Run(exec+"/"+tt.name, func(t *testing.T) {
+				_, err := validateSafeCommand(tt.command, cwd)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("validateSafeCommand(%q) err = %v, wantErr %t", tt.command, err, tt.wantErr)
+				}
+			})
+		}
+	}
+}
+
+// 自带的 config.yaml 会整体替换内置的命令列表与 denyPaths，其中保留的命令与路径不得比内置规则宽松
+func TestShippedShellConfig(t *testing.T) {
+	cfg := consts.Config.Shell
+	if cfg == nil {
+		t.Fatal("config.yaml 中缺少 shell 配置")
+	}
+	for _, glob := range defaultDenyPaths {
+		if !slices.Contains(cfg.DenyPaths, glob) {
+			t.Errorf("shell.denyPaths 缺少内置的 %s", glob)
+		}
+	}
+	for name, rule := range cfg.Commands {
+		builtin, ok := defaultShellCommands[name]
+		if !ok {
+			continue
+		}
+		for _, flag := range builtin.ForbiddenFlags {
+			if !slices.Contains(rule.ForbiddenFlags, flag) {
+				t.Errorf("shell.commands.%s 缺少内置禁止的选项 %s", name, flag)
+			}
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	setShellPolicy(t, cfg)
+	cwd := t.TempDir()
+	for _, command := range []string{
+		"grep -r BEGIN " + home,
+		"ls -R " + home,
+		"cat " + filepath.Join(home, ".git-credentials"),
+		"cat " + filepath.Join(home, ".gnupg/x"),
+		"cat /etc/gshadow",
+		"cat " + filepath.Join(home, ".ssh/id_ed25519"),
+	} {
+		if _, err := validateSafeCommand(command, cwd); err == nil {
+			t.Errorf("自带配置下 %q 应被拒绝", command)
+		}
+	}
+}
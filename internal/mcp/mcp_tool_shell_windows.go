@@ -0,0 +1,8 @@
+//go:build windows
+
+package mcp
+
+import "os/exec"
+
+// setProcessGroup Windows 下使用默认行为，取消时结束 shell 进程
+func setProcessGroup(cmd *exec.Cmd) {}
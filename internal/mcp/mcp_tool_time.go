@@ -1,72 +1,79 @@
 package mcp
 
 import (
+	"ai-mcp/internal/model"
 	"context"
-	"errors"
 	"fmt"
+	"time"
+	// 内置时区数据库，精简镜像中没有 /usr/share/zoneinfo 时 timeZones 中的时区仍可加载
+	_ "time/tzdata"
 
-	"github.com/gogf/gf/v2/encoding/gjson"
-	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/os/gtime"
-	"github.com/gogf/gf/v2/util/gconv"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
+// timeZones NowTime 支持的时区（IANA 名称）
+var timeZones = []string{
+	"UTC",
+	"Asia/Shanghai", "Asia/Hong_Kong", "Asia/Taipei", "Asia/Tokyo", "Asia/Seoul", "Asia/Singapore",
+	"Asia/Bangkok", "Asia/Jakarta", "Asia/Kolkata", "Asia/Dubai",
+	"Europe/London", "Europe/Paris", "Europe/Berlin", "Europe/Amsterdam", "Europe/Madrid", "Europe/Rome", "Europe/Moscow",
+	"Africa/Cairo", "Africa/Johannesburg",
+	"America/New_York", "America/Chicago", "America/Denver", "America/Los_Angeles", "America/Anchorage",
+	"America/Toronto", "America/Mexico_City", "America/Sao_Paulo",
+	"Australia/Sydney", "Pacific/Auckland", "Pacific/Honolulu",
+}
+
+// GetNowTime 获取指定时区的当前时间
 func (s *sMcpTool) GetNowTime(ctx context.Context, request mcp.CallToolRequest) (out *mcp.CallToolResult, err error) {
-	now := gtime.Now()
-	// 获取指定时区
-	out = mcp.NewToolResultText(gjson.MustEncodeString(g.Map{
-		"datetime":        now.Format("Y-m-d H:i:s"),
-		"UnixMillisecond": now.UnixMilli(),
-	}))
+	timeZone := request.GetString("timeZone", "")
+	location, err := time.LoadLocation(timeZone)
+	if err != nil || timeZone == "" {
+		out = toolError(ErrCodeInvalidArgument, "unknown timeZone: %s", timeZone)
+		err = nil
+		return
+	}
+	now := gtime.Now().ToLocation(location)
+	out = mcp.NewToolResultStructuredOnly(model.NowTimeOutput{
+		TimeZone:        timeZone,
+		Datetime:        now.Format("Y-m-d H:i:s"),
+		UnixMillisecond: now.UnixMilli(),
+	})
 	return
 }
 
 // TimestampToDateTime 时间戳转换为日期时间
 func (s *sMcpTool) TimestampToDateTime(ctx context.Context, request mcp.CallToolRequest) (out *mcp.CallToolResult, err error) {
-	timestamp := request.GetString("timestamp", "")
-	if timestamp == "" {
-		err = errors.New("timestamp is empty")
+	timestamp := request.GetInt("timestamp", 0)
+	if timestamp <= 0 {
+		out = toolError(ErrCodeInvalidArgument, "timestamp is empty")
 		return
 	}
-	gtime.NewFromTimeStamp(gconv.Int64(timestamp)).Format("Y-m-d H:i:s")
-	out = mcp.NewToolResultText(gjson.MustEncodeString(g.Map{
-		"datetime": gtime.NewFromTimeStamp(gconv.Int64(timestamp)).Format("Y-m-d H:i:s"),
-	}))
+	out = mcp.NewToolResultStructuredOnly(model.TimestampToDateTimeOutput{
+		Datetime: gtime.NewFromTimeStamp(int64(timestamp)).Format("Y-m-d H:i:s"),
+	})
 	return
 }
 
 // GetCalendarDays 获取指定年月的每一天日期
 func (s *sMcpTool) GetCalendarDays(ctx context.Context, request mcp.CallToolRequest) (out *mcp.CallToolResult, err error) {
-	year := request.GetString("year", "")
-	month := request.GetString("month", "")
-
-	if year == "" {
-		err = errors.New("year is required")
-		return
-	}
-	if month == "" {
-		err = errors.New("month is required")
-		return
-	}
-
-	yearInt := gconv.Int(year)
-	monthInt := gconv.Int(month)
+	yearInt := request.GetInt("year", 0)
+	monthInt := request.GetInt("month", 0)
 
 	// 验证年份和月份的有效性
 	if yearInt < 1 || yearInt > 9999 {
-		err = errors.New("year must be between 1 and 9999")
+		out = toolError(ErrCodeInvalidArgument, "year must be between 1 and 9999")
 		return
 	}
 	if monthInt < 1 || monthInt > 12 {
-		err = errors.New("month must be between 1 and 12")
+		out = toolError(ErrCodeInvalidArgument, "month must be between 1 and 12")
 		return
 	}
 
 	// 获取该月的第一天
 	firstDay := gtime.NewFromStr(fmt.Sprintf("%d-%02d-01", yearInt, monthInt))
 	if firstDay == nil {
-		err = errors.New("invalid date")
+		out = toolError(ErrCodeInvalidArgument, "invalid date")
 		return
 	}
 
@@ -79,27 +86,25 @@ func (s *sMcpTool) GetCalendarDays(ctx context.Context, request mcp.CallToolRequ
 	}
 
 	// 生成该月所有日期
-	var days []g.Map
+	var days []model.CalendarDay
 	currentDay := firstDay
 	for currentDay.Before(lastDay) || currentDay.Equal(lastDay) {
-		days = append(days, g.Map{
-			"date":        currentDay.Format("Y-m-d"),
-			"day":         currentDay.Day(),
-			"weekday":     currentDay.Weekday(),
-			"weekdayName": currentDay.Format("l"),
-			"isWeekend":   currentDay.Weekday() == 0 || currentDay.Weekday() == 6, // 0=Sunday, 6=Saturday
+		days = append(days, model.CalendarDay{
+			Date:        currentDay.Format("Y-m-d"),
+			Day:         currentDay.Day(),
+			Weekday:     int(currentDay.Weekday()),
+			WeekdayName: currentDay.Format("l"),
+			IsWeekend:   currentDay.Weekday() == 0 || currentDay.Weekday() == 6, // 0=Sunday, 6=Saturday
 		})
 		currentDay = currentDay.AddDate(0, 0, 1)
 	}
 
-	result := g.Map{
-		"year":      yearInt,
-		"month":     monthInt,
-		"monthName": firstDay.Format("F"),
-		"totalDays": len(days),
-		"days":      days,
-	}
-
-	out = mcp.NewToolResultText(gjson.MustEncodeString(result))
+	out = mcp.NewToolResultStructuredOnly(model.CalendarDaysOutput{
+		Year:      yearInt,
+		Month:     monthInt,
+		MonthName: firstDay.Format("F"),
+		TotalDays: len(days),
+		Days:      days,
+	})
 	return
 }
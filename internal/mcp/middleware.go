@@ -0,0 +1,328 @@
+package mcp
+
+import (
+	"ai-mcp/internal/auth"
+	"ai-mcp/internal/consts"
+	"ai-mcp/internal/metrics"
+	"ai-mcp/internal/model"
+	"ai-mcp/internal/redact"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gogf/gf/v2/net/gtrace"
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Middleware 工具调用中间件，与 server.ToolHandlerFunc 组合形成调用链
+type Middleware func(next server.ToolHandlerFunc) server.ToolHandlerFunc
+
+// 内置中间件名称，middleware.chain 中按名称引用
+const (
+	MiddlewareRecovery  = "recovery"
+	MiddlewareLogging   = "logging"
+	MiddlewareAuth      = "auth"
+	MiddlewareRateLimit = "rateLimit"
+	MiddlewareTimeout   = "timeout"
+)
+
+// defaultChain 未配置 middleware.chain 时的执行顺序，靠前的位于外层
+var defaultChain = []string{MiddlewareRecovery, MiddlewareLogging, MiddlewareAuth, MiddlewareRateLimit, MiddlewareTimeout}
+
+// builtinMiddlewares 内置中间件的构造函数，组装调用链时各创建一次，所有工具共享同一实例（如限流令牌桶）
+var builtinMiddlewares = map[string]func() Middleware{
+	MiddlewareRecovery:  func() Middleware { return recoveryMiddleware },
+	MiddlewareLogging:   func() Middleware { return loggingMiddleware },
+	MiddlewareAuth:      func() Middleware { return authMiddleware },
+	MiddlewareRateLimit: newRateLimitMiddleware,
+	MiddlewareTimeout:   newTimeoutMiddleware,
+}
+
+var (
+	middlewareMu sync.Mutex
+	// customMiddlewares RegisterMiddleware 注册的具名中间件，同名时优先于内置中间件
+	customMiddlewares = map[string]Middleware{}
+	// extraMiddlewares 通过 Use 追加的中间件，位于配置链内层
+	extraMiddlewares []Middleware
+	chainOnce        sync.Once
+	chainList        []Middleware
+)
+
+// RegisterMiddleware 注册具名中间件，需在 GetMcpFn 之前调用，之后可在 middleware.chain 中引用
+// 同名注册会覆盖内置中间件
+func (s *sMcpHandler) RegisterMiddleware(name string, mw Middleware) {
+	middlewareMu.Lock()
+	defer middlewareMu.Unlock()
+	customMiddlewares[name] = mw
+}
+
+// Use 追加中间件，按调用顺序位于配置链之后（更靠近工具处理函数），需在 GetMcpFn 之前调用
+func (s *sMcpHandler) Use(mw ...Middleware) {
+	middlewareMu.Lock()
+	defer middlewareMu.Unlock()
+	extraMiddlewares = append(extraMiddlewares, mw...)
+}
+
+// chain 按 middleware.chain 配置组装中间件，首次调用后固定，未知名称记录警告后忽略
+func (s *sMcpHandler) chain() []Middleware {
+	chainOnce.Do(func() {
+		middlewareMu.Lock()
+		defer middlewareMu.Unlock()
+		names := defaultChain
+		if cfg := consts.Config.Middleware; cfg != nil && len(cfg.Chain) > 0 {
+			names = cfg.Chain
+		}
+		for _, name := range names {
+			if mw, ok := customMiddlewares[name]; ok {
+				chainList = append(chainList, mw)
+			} else if newMw, ok := builtinMiddlewares[name]; ok {
+				chainList = append(chainList, newMw())
+			} else {
+				consts.Logger.Warningf(consts.Ctx, "未知的中间件 %s，已忽略", name)
+			}
+		}
+		chainList = append(chainList, extraMiddlewares...)
+	})
+	return chainList
+}
+
+// wrap 用中间件依次包装处理函数，list[0] 位于最外层
+func wrap(handler server.ToolHandlerFunc, list []Middleware) server.ToolHandlerFunc {
+	for i := len(list) - 1; i >= 0; i-- {
+		handler = list[i](handler)
+	}
+	return handler
+}
+
+// callState 单次工具调用的状态，供中间件向外层的指标、审计记录调用结果分类。
+// timeout 中间件的工作协程可能在外层读取时写入，字段均为原子值
+type callState struct {
+	outcome atomic.Value // string，调用结果分类
+	panic   atomic.Value // string，捕获到的 panic 信息，写入审计记录
+	running atomic.Value // chan struct{}，timeout 放弃等待后仍在后台运行的处理函数，结束时关闭
+}
+
+type ctxKeyCallState struct{}
+
+func newCallState() *callState {
+	state := &callState{}
+	state.outcome.Store(metrics.OutcomeSuccess)
+	return state
+}
+
+func (s *callState) Outcome() string {
+	outcome, _ := s.outcome.Load().(string)
+	return outcome
+}
+
+func (s *callState) Panic() string {
+	value, _ := s.panic.Load().(string)
+	return value
+}
+
+func withCallState(ctx context.Context, state *callState) context.Context {
+	return context.WithValue(ctx, ctxKeyCallState{}, state)
+}
+
+// SetOutcome 标记本次调用的结果分类（如 denied / throttled），用于指标、链路追踪与审计
+func SetOutcome(ctx context.Context, outcome string) {
+	if state, ok := ctx.Value(ctxKeyCallState{}).(*callState); ok {
+		state.outcome.Store(outcome)
+	}
+}
+
+// afterHandler 在工具处理函数结束后执行 fn；timeout 放弃等待的处理函数仍在后台运行时延迟到其结束，
+// 使优雅关闭的等待与最大并发限制覆盖这些调用
+func afterHandler(ctx context.Context, fn func()) {
+	if state, ok := ctx.Value(ctxKeyCallState{}).(*callState); ok {
+		if running, _ := state.running.Load().(chan struct{}); running != nil {
+			go func() {
+				<-running
+				fn()
+			}()
+			return
+		}
+	}
+	fn()
+}
+
+// recoveryMiddleware 捕获工具处理函数及内层中间件中的 panic，转换为 IsError 的工具调用结果
+func recoveryMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				result, err = recoverResult(ctx, request.Params.Name, r), nil
+			}
+		}()
+		return next(ctx, request)
+	}
+}
+
+// recoverResult 记录 panic 的堆栈，返回带关联 ID 的错误结果；堆栈只写入服务端日志，不返回给客户端
+func recoverResult(ctx context.Context, toolName string, r any) *mcp.CallToolResult {
+	correlationId := gtrace.GetTraceID(ctx)
+	if correlationId == "" {
+		correlationId = uuid.NewString()
+	}
+	SetOutcome(ctx, metrics.OutcomePanic)
+	if state, ok := ctx.Value(ctxKeyCallState{}).(*callState); ok {
+		state.panic.Store(fmt.Sprintf("%v", r))
+	}
+	consts.Logger.Errorf(ctx, "工具 %s 发生 panic，关联 ID: %s，错误: %v\n%s", toolName, correlationId, r, debug.Stack())
+	result := toolError(ErrCodeInternal, "工具 %s 执行时发生内部错误：%v（关联 ID: %s）", toolName, r, correlationId)
+	result.Meta.AdditionalFields["correlationId"] = correlationId
+	return result
+}
+
+// loggingMiddleware 记录调用方、工具名、脱敏后的参数与耗时
+func loggingMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+		name := request.Params.Name
+		consts.Logger.Printf(ctx, "调用方 %s 使用工具 %s 请求内容 %+v", auth.GetIdentity(ctx), name, redact.Args(name, request.GetArguments()))
+		start := time.Now()
+		result, err = next(ctx, request)
+		consts.Logger.Printf(ctx, "工具 %s 调用结束，耗时 %s", name, time.Since(start))
+		return
+	}
+}
+
+// authMiddleware 按调用方授权配置校验工具使用权限。GetMcpFn 始终在中间件链最内层再校验一次，
+// 链中的 auth 只决定校验的位置（如位于 rateLimit 之前时被拒绝的调用不消耗令牌）
+func authMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name := request.Params.Name
+		if !auth.Auth.ToolAllowed(ctx, name) {
+			consts.Logger.Warningf(ctx, "调用方 %s 无权使用工具 %s", auth.GetIdentity(ctx), name)
+			SetOutcome(ctx, metrics.OutcomeDenied)
+			return toolError(ErrCodePermissionDenied, "无权使用工具 %s", name), nil
+		}
+		return next(ctx, request)
+	}
+}
+
+// newRateLimitMiddleware 按全局、工具、调用方令牌桶限流并限制并发，所有工具共享同一组限流器
+// 超出时立即返回带重试等待时间的错误结果而不阻塞会话
+func newRateLimitMiddleware() Middleware {
+	limiter := newRateLimiter(middlewareConfig().RateLimit)
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			if limiter == nil {
+				return next(ctx, request)
+			}
+			identity := auth.GetIdentity(ctx)
+			release, scope, retryAfter, ok := limiter.acquire(request.Params.Name, identity)
+			if !ok {
+				SetOutcome(ctx, metrics.OutcomeThrottled)
+				seconds := math.Ceil(retryAfter.Seconds()*10) / 10
+				consts.Logger.Warningf(ctx, "调用方 %s 调用工具 %s 触发限流（%s）", identity, request.Params.Name, scope)
+				result := toolError(ErrCodeRateLimited, "调用过于频繁，触发限流（%s），请在 %.1f 秒后重试", scope, seconds)
+				result.Meta.AdditionalFields["retryAfterSeconds"] = seconds
+				return result, nil
+			}
+			defer afterHandler(ctx, release)
+			return next(ctx, request)
+		}
+	}
+}
+
+// defaultToolTimeouts 内置的工具超时时间，RunSafeShellCommand 自身限制最长 60 秒，外层留出结束进程组的时间
+var defaultToolTimeouts = map[string]int{
+	"RunSafeShellCommand": 75,
+}
+
+// toolTimeout 获取工具的超时时间：timeout.tools > 内置工具超时 > timeout.default（默认 60 秒），小于 0 表示不限制
+func toolTimeout(name string) time.Duration {
+	cfg := model.TimeoutConfig{}
+	if middlewareConfig().Timeout != nil {
+		cfg = *middlewareConfig().Timeout
+	}
+	seconds, ok := cfg.Tools[name]
+	if !ok {
+		if seconds, ok = defaultToolTimeouts[name]; !ok {
+			seconds = cfg.Default
+		}
+	}
+	if seconds == 0 {
+		seconds = 60
+	}
+	if seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// abandonWait 超时或取消后等待工具处理函数响应上下文取消并返回的时长
+var abandonWait = 5 * time.Second
+
+// newTimeoutMiddleware 为工具调用设置执行时限；超时或客户端取消后最多再等待 abandonWait 让工具返回，
+// 数据库查询、shell 进程组等会随上下文取消而中断。仍未返回的工具转入后台运行，直到其结束前
+// 继续占用并发限制并被优雅关闭等待
+func newTimeoutMiddleware() Middleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			name := request.Params.Name
+			timeout := toolTimeout(name)
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			type response struct {
+				result *mcp.CallToolResult
+				err    error
+			}
+			ch := make(chan response, 1)
+			finished := make(chan struct{})
+			go func() {
+				defer close(finished)
+				defer func() {
+					if r := recover(); r != nil {
+						ch <- response{result: recoverResult(ctx, name, r)}
+					}
+				}()
+				result, err := next(ctx, request)
+				ch <- response{result, err}
+			}()
+
+			select {
+			case resp := <-ch:
+				if ctx.Err() == nil {
+					return resp.result, resp.err
+				}
+			case <-ctx.Done():
+				select {
+				case <-finished:
+				case <-time.After(abandonWait):
+					consts.Logger.Warningf(ctx, "工具 %s 在取消后 %s 内未返回，转入后台运行", name, abandonWait)
+					if state, ok := ctx.Value(ctxKeyCallState{}).(*callState); ok {
+						state.running.Store(finished)
+					}
+				}
+			}
+			if errors.Is(context.Cause(ctx), errCancelledByClient) {
+				SetOutcome(ctx, metrics.OutcomeCancelled)
+				return toolError(ErrCodeCancelled, "工具 %s 已被客户端取消", name), nil
+			}
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				SetOutcome(ctx, metrics.OutcomeTimeout)
+				return toolError(ErrCodeTimeout, "工具 %s 执行超时（%s）", name, timeout), nil
+			}
+			return toolError(ErrCodeCancelled, "工具 %s 已取消：%s", name, context.Cause(ctx)), nil
+		}
+	}
+}
+
+func middlewareConfig() (cfg model.MiddlewareConfig) {
+	if consts.Config.Middleware != nil {
+		cfg = *consts.Config.Middleware
+	}
+	return
+}
@@ -0,0 +1,165 @@
+package mcp
+
+import (
+	"ai-mcp/internal/auth"
+	"ai-mcp/internal/consts"
+	"ai-mcp/internal/metrics"
+	"ai-mcp/internal/model"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	// /metrics 采集数据库连接池指标时需要驱动，与 main.go 一致
+	_ "github.com/gogf/gf/contrib/drivers/mysql/v2"
+)
+
+// scrapeMetrics 读取 /metrics 的文本输出
+func scrapeMetrics(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+// sampleArgs 按参数定义为必填参数生成合法取值，保证调用能通过参数校验到达处理函数
+func sampleArgs(schema mcp.ToolInputSchema) map[string]any {
+	args := map[string]any{}
+	for _, name := range schema.Required {
+		property, _ := schema.Properties[name].(map[string]any)
+		if enum, ok := property["enum"].([]string); ok && len(enum) > 0 {
+			args[name] = enum[0]
+			continue
+		}
+		switch property["type"] {
+		case "number", "integer":
+			value := 1.0
+			if minimum, ok := property["minimum"].(float64); ok {
+				value = minimum
+			}
+			args[name] = value
+		case "boolean":
+			args[name] = true
+		case "array":
+			args[name] = []any{"x"}
+		default:
+			args[name] = "x"
+		}
+	}
+	return args
+}
+
+func TestToolPanicRecovery(t *testing.T) {
+	authCfg := consts.Config.McpServer.Auth
+	consts.Config.McpServer.Auth = nil
+	t.Cleanup(func() {
+		consts.Config.McpServer.Auth = authCfg
+	})
+
+	chains := []struct {
+		name  string
+		chain []string
+	}{
+		{name: "默认链", chain: defaultChain}, // panic 发生在 timeout 的工作协程中
+		{name: "仅 recovery", chain: []string{MiddlewareRecovery, MiddlewareAuth}},
+		{name: "不含 recovery", chain: []string{MiddlewareLogging}}, // 由 GetMcpFn 兜底
+	}
+	for i, tt := range chains {
+		t.Run(tt.name, func(t *testing.T) {
+			setChain(t, tt.chain, nil)
+			caller := fmt.Sprintf("panic-%d", i)
+			ctx := auth.WithIdentity(context.Background(), &model.Identity{Name: caller, Method: "test"})
+
+			for _, item := range McpHandler.GetList() {
+				item.Fn = func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+					panic("boom in " + request.Params.Name)
+				}
+				args := sampleArgs(McpHandler.NewTool(&item).InputSchema)
+				result, err := callTool(McpHandler.GetMcpFn(&item), ctx, item.Name, args)
+				if err != nil {
+					t.Fatalf("%s: 返回了 error: %v", item.Name, err)
+				}
+				if result == nil || !result.IsError {
+					t.Fatalf("%s: 期望 isError 结果，得到 %+v", item.Name, result)
+				}
+				text := result.Content[0].(mcp.TextContent).Text
+				if !strings.HasPrefix(text, ErrCodeInternal) {
+					t.Errorf("%s: 结果 %q 未以 %s 开头", item.Name, text, ErrCodeInternal)
+				}
+				correlationId, _ := result.Meta.AdditionalFields["correlationId"].(string)
+				if correlationId == "" || !strings.Contains(text, correlationId) {
+					t.Errorf("%s: _meta.correlationId = %q，结果文本 %q", item.Name, correlationId, text)
+				}
+			}
+
+			// 每个工具的调用结果都被归类为 panic
+			body := scrapeMetrics(t)
+			for _, item := range McpHandler.GetList() {
+				series := fmt.Sprintf(`ai_mcp_tool_calls_total{caller="test:%s",outcome="%s",tool="%s"} 1`, caller, metrics.OutcomePanic, item.Name)
+				if !strings.Contains(body, series) {
+					t.Errorf("%s: 指标中缺少 %s", item.Name, series)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeoutWaitsForAbandonedHandler(t *testing.T) {
+	setChain(t, []string{MiddlewareRecovery, MiddlewareRateLimit, MiddlewareTimeout}, nil)
+	consts.Config.Middleware.Timeout = &model.TimeoutConfig{Tools: map[string]int{"SlowTool": 1}}
+	consts.Config.Middleware.RateLimit = &model.RateLimitConfig{RateLimitRule: model.RateLimitRule{MaxConcurrent: 1}}
+	wait := abandonWait
+	abandonWait = 100 * time.Millisecond
+	t.Cleanup(func() {
+		abandonWait = wait
+	})
+
+	// 处理函数忽略上下文，放行后 panic，模拟取消后仍在运行且稍后出错的工具
+	unblock := make(chan struct{})
+	item := model.McpReg{Name: "SlowTool", Fn: func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		<-unblock
+		panic("late panic")
+	}}
+	fn := McpHandler.GetMcpFn(&item)
+	ctx := auth.WithIdentity(context.Background(), &model.Identity{Name: "slow", Method: "test"})
+
+	start := time.Now()
+	result, _ := callTool(fn, ctx, item.Name, nil)
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.HasPrefix(text, ErrCodeTimeout) {
+		t.Fatalf("期望超时，得到 %q", text)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second+abandonWait {
+		t.Errorf("超时后未等待处理函数，耗时 %s", elapsed)
+	}
+
+	// 被放弃的处理函数仍占用并发名额
+	result, _ = callTool(fn, ctx, item.Name, nil)
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.HasPrefix(text, ErrCodeRateLimited) {
+		t.Fatalf("后台运行的调用应继续占用并发名额，得到 %q", text)
+	}
+
+	// 处理函数结束后释放名额；其 panic 写入调用状态不应与外层读取产生数据竞争（go test -race）
+	close(unblock)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		result, _ = callTool(fn, ctx, item.Name, nil)
+		text := result.Content[0].(mcp.TextContent).Text
+		if strings.HasPrefix(text, ErrCodeInternal) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("处理函数结束后并发名额未释放，得到 %q", text)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,162 @@
+package mcp
+
+import (
+	"ai-mcp/internal/consts"
+	"ai-mcp/internal/model"
+	"sync"
+	"time"
+)
+
+// tokenBucket 令牌桶，rate 为每秒补充的令牌数，burst 为桶容量
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst <= 0 {
+		burst = max(1, int(rate))
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// take 尝试取出一个令牌，失败时返回距离下一个令牌可用的等待时长
+func (b *tokenBucket) take() (ok bool, retryAfter time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// refund 归还一个令牌，用于多级限流中后续级别拒绝时撤销已取出的令牌
+func (b *tokenBucket) refund() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.burst, b.tokens+1)
+}
+
+// limiter 单个维度（全局、工具或调用方）的令牌桶与并发信号量，未配置的部分为空
+type limiter struct {
+	scope  string
+	bucket *tokenBucket
+	sem    chan struct{}
+}
+
+func newLimiter(scope string, rule *model.RateLimitRule) *limiter {
+	if rule == nil || (rule.Rate <= 0 && rule.MaxConcurrent <= 0) {
+		return nil
+	}
+	l := &limiter{scope: scope}
+	if rule.Rate > 0 {
+		l.bucket = newTokenBucket(rule.Rate, rule.Burst)
+	}
+	if rule.MaxConcurrent > 0 {
+		l.sem = make(chan struct{}, rule.MaxConcurrent)
+	}
+	return l
+}
+
+// rateLimiter 按全局、工具、调用方三个维度限流与限制并发
+type rateLimiter struct {
+	global      *limiter
+	tools       map[string]*limiter
+	callerRules map[string]*model.RateLimitRule
+	callers     sync.Map // 调用方 -> *limiter，按需创建
+}
+
+// concurrencyRetryAfter 并发已满时建议的重试等待时间
+const concurrencyRetryAfter = time.Second
+
+func newRateLimiter(cfg *model.RateLimitConfig) *rateLimiter {
+	if cfg == nil {
+		return nil
+	}
+	r := &rateLimiter{
+		global:      newLimiter("全局", &cfg.RateLimitRule),
+		tools:       map[string]*limiter{},
+		callerRules: cfg.Callers,
+	}
+	known := map[string]bool{}
+	for _, item := range McpHandler.GetList() {
+		known[item.Name] = true
+	}
+	for name, rule := range cfg.Tools {
+		if !known[name] {
+			consts.Logger.Warningf(consts.Ctx, "限流配置中的工具 %s 不存在，已忽略", name)
+			continue
+		}
+		if l := newLimiter("工具 "+name, rule); l != nil {
+			r.tools[name] = l
+		}
+	}
+	if r.global == nil && len(r.tools) == 0 && len(r.callerRules) == 0 {
+		return nil
+	}
+	return r
+}
+
+// callerLimiter 获取调用方的限流器，规则匹配顺序：method:name > name > *（每个调用方独立计数）
+func (r *rateLimiter) callerLimiter(identity *model.Identity) *limiter {
+	key := identity.String()
+	if l, ok := r.callers.Load(key); ok {
+		return l.(*limiter)
+	}
+	rule, ok := r.callerRules[key]
+	if !ok && identity != nil {
+		rule, ok = r.callerRules[identity.Name]
+	}
+	if !ok {
+		rule = r.callerRules["*"]
+	}
+	l, _ := r.callers.LoadOrStore(key, newLimiter("调用方 "+key, rule))
+	return l.(*limiter)
+}
+
+// acquire 依次检查各维度的令牌与并发，全部通过时返回 release；被拒绝时返回拒绝的维度与建议的重试等待时间
+func (r *rateLimiter) acquire(tool string, identity *model.Identity) (release func(), scope string, retryAfter time.Duration, ok bool) {
+	limiters := []*limiter{r.global, r.tools[tool], r.callerLimiter(identity)}
+	var taken []*tokenBucket
+	var held []chan struct{}
+	release = func() {
+		for _, sem := range held {
+			<-sem
+		}
+	}
+	rollback := func() {
+		for _, bucket := range taken {
+			bucket.refund()
+		}
+		release()
+	}
+	for _, l := range limiters {
+		if l == nil {
+			continue
+		}
+		if l.bucket != nil {
+			if got, wait := l.bucket.take(); !got {
+				rollback()
+				return nil, l.scope, wait, false
+			}
+			taken = append(taken, l.bucket)
+		}
+		if l.sem != nil {
+			select {
+			case l.sem <- struct{}{}:
+				held = append(held, l.sem)
+			default:
+				rollback()
+				return nil, l.scope + " 并发", concurrencyRetryAfter, false
+			}
+		}
+	}
+	return release, "", 0, true
+}
@@ -0,0 +1,109 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runShell 交给 shell 执行命令（shell.exec 为 shell 时使用），命令已通过语法树校验
+func runShell(ctx context.Context, shell, command, dir string, stdout, stderr io.Writer) error {
+	cmd := exec.CommandContext(ctx, shell, "-c", command)
+	prepareCommand(cmd, dir)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
+// runPipeline 不经过 shell，按解析出的参数依次启动管道中的进程，前一个进程的标准输出接到后一个进程的标准输入。
+// 与 shell 一致，返回最后一个进程的执行结果
+func runPipeline(ctx context.Context, pipeline []shellStage, dir string, stdout, stderr io.Writer) (err error) {
+	// 父进程持有的管道端，子进程全部启动后关闭，保证上游退出后下游能读到 EOF、下游退出后上游收到 SIGPIPE
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+
+	cmds := make([]*exec.Cmd, len(pipeline))
+	var stdin io.Reader
+	for i, stage := range pipeline {
+		cmd := exec.CommandContext(ctx, stage.argv[0], stage.argv[1:]...)
+		prepareCommand(cmd, dir)
+		cmd.Stdin = stdin
+
+		out, errOut := stdout, stderr
+		if i < len(pipeline)-1 {
+			r, w, pipeErr := os.Pipe()
+			if pipeErr != nil {
+				return pipeErr
+			}
+			files = append(files, r, w)
+			out, stdin = w, r
+		}
+		// 按出现顺序应用 2>&1、1>&2
+		for _, dup := range stage.dups {
+			switch {
+			case dup.fd == 2 && dup.target == 1:
+				errOut = out
+			case dup.fd == 1 && dup.target == 2:
+				out = errOut
+			}
+		}
+		cmd.Stdout, cmd.Stderr = out, errOut
+		cmds[i] = cmd
+	}
+
+	for i, cmd := range cmds {
+		if err = cmd.Start(); err != nil {
+			// 结束已启动的进程，避免其阻塞在管道上
+			for _, started := range cmds[:i] {
+				_ = started.Cancel()
+				_ = started.Wait()
+			}
+			return
+		}
+	}
+	for _, f := range files {
+		_ = f.Close()
+	}
+	files = nil
+
+	for i, cmd := range cmds {
+		if waitErr := cmd.Wait(); i == len(cmds)-1 {
+			err = waitErr
+		}
+	}
+	return
+}
+
+// prepareCommand 设置工作目录与进程组
+func prepareCommand(cmd *exec.Cmd, dir string) {
+	cmd.Dir = dir
+	setProcessGroup(cmd)
+	// 进程被结束后，最多再等待 2 秒回收输出管道，避免孙进程持有管道导致 Wait 阻塞
+	cmd.WaitDelay = 2 * time.Second
+}
+
+// outputBuffer 管道中的多个进程可能同时写入标准错误，写入需要加锁
+type outputBuffer struct {
+	mu sync.Mutex
+	sb strings.Builder
+}
+
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sb.Write(p)
+}
+
+func (b *outputBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sb.String()
+}
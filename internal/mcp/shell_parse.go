@@ -0,0 +1,204 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// 未加引号的 {a,b} / {1..3}，bash 会做花括号展开，解析器不识别，统一拒绝
+var braceExpansion = regexp.MustCompile(`\{[^{}]*(,|\.\.)[^{}]*\}`)
+
+// shellStage 管道中的一段命令
+type shellStage struct {
+	argv []string // 展开（去引号、~ 与通配符展开）后的参数，与 shell 实际执行时传入的参数一致
+	dups []fdDup  // 按出现顺序记录的 2>&1、1>&2
+}
+
+// fdDup 文件描述符复制，fd 指向 target 当前指向的位置
+type fdDup struct {
+	fd, target int
+}
+
+// parseCommand 按执行命令的 shell 的语法（direct 执行为 POSIX，交给 shell 执行为 bash）解析命令，
+// 只接受由 | 连接的简单命令，返回管道中的每段命令
+func parseCommand(command, cwd string, lang syntax.LangVariant) (pipeline []shellStage, err error) {
+	file, err := syntax.NewParser(syntax.Variant(lang)).Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil, fmt.Errorf("命令语法错误: %v", err)
+	}
+	if len(file.Stmts) != 1 {
+		return nil, errors.New("只允许执行单条命令")
+	}
+	if cwd == "" {
+		cwd, _ = os.Getwd()
+	}
+	home, _ := os.UserHomeDir()
+	cfg := &expand.Config{
+		Env:      expand.ListEnviron("PWD="+cwd, "HOME="+home),
+		ReadDir2: os.ReadDir,
+	}
+	err = collectPipeline(cfg, file.Stmts[0], &pipeline)
+	return
+}
+
+// collectPipeline 校验语句节点并按管道顺序收集每段命令的参数
+func collectPipeline(cfg *expand.Config, stmt *syntax.Stmt, pipeline *[]shellStage) error {
+	switch {
+	case stmt.Background:
+		return errors.New("命令包含被禁用的操作符: &")
+	case stmt.Negated:
+		return errors.New("命令包含被禁用的操作符: !")
+	case stmt.Coprocess:
+		return errors.New("不允许使用协程")
+	}
+	var dups []fdDup
+	for _, redir := range stmt.Redirs {
+		dup, err := validateRedirect(redir)
+		if err != nil {
+			return err
+		}
+		dups = append(dups, dup)
+	}
+	switch cmd := stmt.Cmd.(type) {
+	case *syntax.BinaryCmd:
+		if cmd.Op != syntax.Pipe {
+			return errors.New("命令包含被禁用的操作符: " + cmd.Op.String())
+		}
+		if err := collectPipeline(cfg, cmd.X, pipeline); err != nil {
+			return err
+		}
+		return collectPipeline(cfg, cmd.Y, pipeline)
+	case *syntax.CallExpr:
+		argv, err := expandCall(cfg, cmd)
+		if err != nil {
+			return err
+		}
+		*pipeline = append(*pipeline, shellStage{argv: argv, dups: dups})
+		return nil
+	case nil:
+		return errors.New("无效的空命令")
+	default:
+		return fmt.Errorf("只允许由 | 连接的简单命令，不支持 %s", syntaxName(cmd))
+	}
+}
+
+// expandCall 校验并展开简单命令的每个参数
+func expandCall(cfg *expand.Config, call *syntax.CallExpr) (argv []string, err error) {
+	if len(call.Assigns) > 0 {
+		return nil, errors.New("不允许在命令前设置环境变量: " + call.Assigns[0].Name.Value)
+	}
+	if len(call.Args) == 0 {
+		return nil, errors.New("无效的空命令")
+	}
+	for i, word := range call.Args {
+		if err = validateWord(word.Parts); err != nil {
+			return
+		}
+		if i == 0 && hasUnquotedGlob(word) {
+			return nil, errors.New("可执行文件不允许使用通配符: " + wordSource(word))
+		}
+		fields, expandErr := expand.Fields(cfg, word)
+		if expandErr != nil {
+			return nil, fmt.Errorf("无法展开参数 %s: %v", wordSource(word), expandErr)
+		}
+		argv = append(argv, fields...)
+	}
+	if len(argv) == 0 {
+		return nil, errors.New("无效的空命令")
+	}
+	return
+}
+
+// validateWord 拒绝变量、命令替换、算术展开等在执行时才能确定取值的部分，
+// 以及 $'...'、$"..." 这类各 shell 处理不一致的引号
+func validateWord(parts []syntax.WordPart) error {
+	const dollarQuote = "不允许使用 $'...' 或 $\"...\" 引号"
+	for i, part := range parts {
+		switch p := part.(type) {
+		case *syntax.Lit:
+			if braceExpansion.MatchString(p.Value) {
+				return errors.New("不允许使用花括号展开: " + p.Value)
+			}
+			// POSIX 语法下 $'...' 被解析为字面量 $ 加单引号，bash 等 shell 却会按转义序列展开
+			if i+1 < len(parts) && endsWithUnescapedDollar(p.Value) {
+				switch parts[i+1].(type) {
+				case *syntax.SglQuoted, *syntax.DblQuoted:
+					return errors.New(dollarQuote)
+				}
+			}
+		case *syntax.SglQuoted:
+			if p.Dollar {
+				return errors.New(dollarQuote)
+			}
+		case *syntax.DblQuoted:
+			if p.Dollar {
+				return errors.New(dollarQuote)
+			}
+			if err := validateWord(p.Parts); err != nil {
+				return err
+			}
+		case *syntax.ParamExp:
+			return errors.New("不允许使用变量展开: $" + p.Param.Value)
+		case *syntax.CmdSubst:
+			return errors.New("不允许使用命令替换")
+		case *syntax.ArithmExp:
+			return errors.New("不允许使用算术展开")
+		default:
+			return fmt.Errorf("不支持的参数语法: %s", syntaxName(p))
+		}
+	}
+	return nil
+}
+
+// validateRedirect 只允许 2>&1、1>&2 这类在标准输出与标准错误之间的复制，其余重定向一律拒绝
+func validateRedirect(redir *syntax.Redirect) (dup fdDup, err error) {
+	if redir.Op == syntax.DplOut && redir.Word != nil {
+		target := redir.Word.Lit()
+		fd := "1"
+		if redir.N != nil {
+			fd = redir.N.Value
+		}
+		if (fd == "1" || fd == "2") && (target == "1" || target == "2") {
+			return fdDup{fd: int(fd[0] - '0'), target: int(target[0] - '0')}, nil
+		}
+	}
+	return dup, errors.New("命令包含被禁用的重定向: " + redir.Op.String())
+}
+
+// endsWithUnescapedDollar 判断字面量是否以未被 \ 转义的 $ 结尾
+func endsWithUnescapedDollar(value string) bool {
+	if !strings.HasSuffix(value, "$") {
+		return false
+	}
+	escapes := 0
+	for i := len(value) - 2; i >= 0 && value[i] == '\\'; i-- {
+		escapes++
+	}
+	return escapes%2 == 0
+}
+
+// hasUnquotedGlob 判断参数中是否存在未加引号的通配符
+func hasUnquotedGlob(word *syntax.Word) bool {
+	for _, part := range word.Parts {
+		if lit, ok := part.(*syntax.Lit); ok && strings.ContainsAny(lit.Value, "*?[") {
+			return true
+		}
+	}
+	return false
+}
+
+func wordSource(word *syntax.Word) string {
+	var sb strings.Builder
+	_ = syntax.NewPrinter().Print(&sb, word)
+	return sb.String()
+}
+
+func syntaxName(node syntax.Node) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", node), "*syntax.")
+}
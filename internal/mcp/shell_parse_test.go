@@ -0,0 +1,32 @@
+package mcp
+
+import (
+	"testing"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+func TestParseCommandDollarQuotes(t *testing.T) {
+	tests := []struct {
+		command string
+		wantErr bool
+	}{
+		{`cat $'.env'`, true},
+		{`cat $".env"`, true},
+		{`cat a$'b'`, true},
+		{`grep -e x$'\t'`, true},
+		{`cat \$'x'`, false}, // 转义的 $ 是普通字符
+		{`echo "$"'x'`, false},
+		{`echo 'a$'`, false},
+		{`grep 'x$' file`, false},
+	}
+	// 两种语法下结果一致：POSIX 语法把 $'...' 解析为字面量 $ 加单引号，同样要拒绝
+	for _, lang := range []syntax.LangVariant{syntax.LangPOSIX, syntax.LangBash} {
+		for _, tt := range tests {
+			_, err := parseCommand(tt.command, t.TempDir(), lang)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s: parseCommand(%q) err = %v, wantErr %t", lang, tt.command, err, tt.wantErr)
+			}
+		}
+	}
+}
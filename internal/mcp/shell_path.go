@@ -0,0 +1,193 @@
+package mcp
+
+import (
+	"ai-mcp/internal/consts"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// 未配置 shell.denyPaths 时禁止读取的路径：密钥、凭据与系统账户文件
+var defaultDenyPaths = []string{
+	"~/.ssh", "~/.gnupg", "~/.aws", "~/.kube", "~/.docker", "~/.netrc", "~/.git-credentials",
+	"/etc/shadow", "/etc/gshadow", "/etc/passwd", "/etc/sudoers", "/etc/sudoers.d",
+	"/proc/*/environ", "/proc/*/mem",
+	"**/.env", "**/id_rsa*", "**/id_ecdsa*", "**/id_ed25519*", "**/*.pem", "**/*.key",
+}
+
+// pathGlob 编译后的路径 glob，* 与 ? 不跨越 /，** 匹配任意层级
+type pathGlob struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+func compilePathGlobs(patterns []string) (list []pathGlob) {
+	home, _ := os.UserHomeDir()
+	for _, pattern := range patterns {
+		expanded := pattern
+		if home != "" && (expanded == "~" || strings.HasPrefix(expanded, "~/")) {
+			expanded = home + expanded[1:]
+		}
+		re, err := regexp.Compile(globToRegexp(filepath.ToSlash(filepath.Clean(expanded))))
+		if err != nil {
+			consts.Logger.Warningf(consts.Ctx, "shell.denyPaths 中的 %s 无效，已忽略: %v", pattern, err)
+			continue
+		}
+		list = append(list, pathGlob{pattern: pattern, re: re})
+	}
+	return
+}
+
+// globToRegexp 把路径 glob 转为正则，**/ 可匹配零到多层目录；不以 / 开头的 glob 匹配任意位置的路径尾部
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	if strings.HasPrefix(glob, "/") {
+		sb.WriteString("^")
+	} else {
+		sb.WriteString("(^|/)")
+	}
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			if end := strings.IndexByte(glob[i:], ']'); end > 0 {
+				class := glob[i+1 : i+end]
+				if strings.HasPrefix(class, "!") {
+					class = "^" + class[1:]
+				}
+				sb.WriteString("[" + class + "]")
+				i += end
+			} else {
+				sb.WriteString(`\[`)
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
+
+// deniedBy 返回命中的 glob，路径本身或其任一上级目录命中即视为禁止
+func deniedBy(globs []pathGlob, path string) string {
+	path = filepath.ToSlash(path)
+	for {
+		for _, glob := range globs {
+			if glob.re.MatchString(path) {
+				return glob.pattern
+			}
+		}
+		parent := filepath.ToSlash(filepath.Dir(path))
+		if parent == path {
+			return ""
+		}
+		path = parent
+	}
+}
+
+// resolveWorkDir 确定命令的工作目录：cwd 为空时使用默认目录，解析符号链接后必须位于 roots 之内且未被禁止读取
+func (p *shellPolicy) resolveWorkDir(cwd string) (dir string, err error) {
+	base := p.workDir
+	if base == "" {
+		if base, err = os.Getwd(); err != nil {
+			return
+		}
+	}
+	// 相对路径按默认工作目录解析
+	dir = cwd
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(base, dir)
+	}
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		return "", fmt.Errorf("工作目录无效: %w", err)
+	}
+	if info, statErr := os.Stat(dir); statErr != nil || !info.IsDir() {
+		return "", fmt.Errorf("工作目录无效: %s 不是目录", dir)
+	}
+	if len(p.roots) > 0 && !withinRoots(p.roots, dir) {
+		return "", fmt.Errorf("工作目录 %s 不在允许的根目录内（%s）", dir, strings.Join(p.roots, "、"))
+	}
+	if pattern := deniedBy(p.denyPaths, dir); pattern != "" {
+		return "", fmt.Errorf("工作目录 %s 被禁止访问（%s）", dir, pattern)
+	}
+	return
+}
+
+func withinRoots(roots []string, dir string) bool {
+	for _, root := range roots {
+		if rel, err := filepath.Rel(root, dir); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPathArgs 把每个参数都当作可能的路径，按工作目录解析（含符号链接）后与禁止读取的 glob 比对。
+// 选项中附带的值（如 --file=/etc/shadow、-f/etc/shadow、-f.env）同样检查，见 pathCandidates
+func (p *shellPolicy) checkPathArgs(dir string, pipeline []shellStage) error {
+	if len(p.denyPaths) == 0 {
+		return nil
+	}
+	for _, stage := range pipeline {
+		for _, arg := range stage.argv[1:] {
+			for _, candidate := range pathCandidates(arg) {
+				if pattern := p.deniedPath(dir, candidate); pattern != "" {
+					return fmt.Errorf("参数 %s 指向禁止读取的路径（%s）", arg, pattern)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// pathCandidates 返回参数中可能是路径的部分。--name=value 取 = 之后的值；
+// 单横线选项的值可以紧跟在任意一个短选项之后（-f.env、-nf.env），因此第二个字符起的每个后缀都视为候选
+func pathCandidates(arg string) []string {
+	switch {
+	case strings.HasPrefix(arg, "--"):
+		if i := strings.IndexByte(arg, '='); i > 0 && i < len(arg)-1 {
+			return []string{arg[i+1:]}
+		}
+		return nil
+	case strings.HasPrefix(arg, "-"):
+		var candidates []string
+		for i := 2; i < len(arg); i++ {
+			candidates = append(candidates, arg[i:])
+		}
+		if i := strings.IndexByte(arg, '='); i > 0 && i < len(arg)-1 {
+			candidates = append(candidates, arg[i+1:])
+		}
+		return candidates
+	case arg == "":
+		return nil
+	default:
+		return []string{arg}
+	}
+}
+
+// deniedPath 返回命中的禁止 glob，candidate 为相对路径时相对 dir 解析
+func (p *shellPolicy) deniedPath(dir, candidate string) string {
+	if !filepath.IsAbs(candidate) {
+		candidate = dir + string(filepath.Separator) + candidate
+	}
+	// 同时检查字面路径与解析符号链接后的实际路径，后者按目录逐级解析，keys/.. 这类写法以实际位置为准
+	pattern := deniedBy(p.denyPaths, filepath.Clean(candidate))
+	if pattern == "" {
+		if resolved, err := filepath.EvalSymlinks(candidate); err == nil {
+			pattern = deniedBy(p.denyPaths, resolved)
+		}
+	}
+	return pattern
+}
@@ -0,0 +1,64 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckPathArgs(t *testing.T) {
+	p := newShellPolicy(nil)
+	dir := t.TempDir()
+
+	tests := []struct {
+		argv    []string
+		wantErr bool
+	}{
+		{[]string{"cat", ".env"}, true},
+		{[]string{"cat", "/etc/shadow"}, true},
+		{[]string{"grep", "--file=.env", "x"}, true},
+		{[]string{"grep", "-f/etc/shadow", "x"}, true},
+		{[]string{"date", "-f.env"}, true}, // 选项值紧跟在选项之后，既没有 = 也没有 /
+		{[]string{"grep", "-nf.env", "x"}, true},
+		{[]string{"grep", "-f=.env", "x"}, true},
+		{[]string{"cat", "sub/../.env"}, true},
+		{[]string{"cat", "README.md"}, false},
+		{[]string{"head", "-n5", "README.md"}, false},
+		{[]string{"grep", "--color", ".envrc"}, false},
+		{[]string{"grep", "-e", "env", "app.envs"}, false},
+	}
+	for _, tt := range tests {
+		err := p.checkPathArgs(dir, []shellStage{{argv: tt.argv}})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkPathArgs(%q) err = %v, wantErr %t", strings.Join(tt.argv, " "), err, tt.wantErr)
+		}
+	}
+}
+
+func TestDefaultCommandsForbidRecursion(t *testing.T) {
+	p := newShellPolicy(nil)
+
+	tests := []struct {
+		argv    []string
+		wantErr bool
+	}{
+		{[]string{"grep", "-r", "hunter2", "."}, true},
+		{[]string{"grep", "-R", "PRIVATE", "/root"}, true},
+		{[]string{"grep", "-rn", "x", "."}, true},
+		{[]string{"grep", "--recursive", "x", "."}, true},
+		{[]string{"grep", "--rec", "x", "."}, true}, // 长选项缩写
+		{[]string{"grep", "-d", "recurse", "x", "."}, true},
+		{[]string{"grep", "--directories=recurse", "x", "."}, true},
+		{[]string{"ls", "-R", "/"}, true},
+		{[]string{"ls", "-laR"}, true},
+		{[]string{"diff", "-r", "a", "b"}, true},
+		{[]string{"grep", "-n", "x", "file"}, false},
+		{[]string{"ls", "-la"}, false},
+		{[]string{"diff", "-u", "a", "b"}, false},
+	}
+	for _, tt := range tests {
+		err := p.validate([]shellStage{{argv: tt.argv}})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validate(%q) err = %v, wantErr %t", strings.Join(tt.argv, " "), err, tt.wantErr)
+		}
+	}
+}
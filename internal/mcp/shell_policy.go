@@ -0,0 +1,410 @@
+package mcp
+
+import (
+	"ai-mcp/internal/consts"
+	"ai-mcp/internal/model"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// 命令校验模式
+const (
+	ShellModeAllowlist = "allowlist"
+	ShellModeBlacklist = "blacklist"
+)
+
+// 命令执行方式
+const (
+	ShellExecDirect = "direct"
+	ShellExecShell  = "shell"
+)
+
+// exec 为 shell 且未配置 path 时按顺序查找的 shell
+var shellCandidates = []string{"sh", "bash"}
+
+// shellDialects exec 为 shell 时支持的 shell（按解析符号链接后的文件名）。sh 可能就是 bash，
+// 统一按 bash 语法解析，$'...' 等 bash 扩展会被识别并拒绝；zsh 等展开规则不同的 shell 不支持
+var shellDialects = map[string]bool{"sh": true, "bash": true, "dash": true, "ash": true, "busybox": true}
+
+const defaultMaxPipes = 3
+
+// 递归读取目录的选项。路径检查只针对参数本身，递归时目录下被禁止的文件仍会被读取，默认禁止
+var (
+	grepRecursiveFlags = []string{"-r", "-R", "-d", "--recursive", "--dereference-recursive", "--directories*"}
+	lsRecursiveFlags   = []string{"-R", "--recursive"}
+	diffRecursiveFlags = []string{"-r", "--recursive"}
+)
+
+// --files0-from 从文件或标准输入读取要处理的文件名，这些文件名不经过路径检查，默认禁止
+const filesFromFlag = "--files0-from*"
+
+// 未配置 shell.commands 时使用的只读命令白名单。
+// 不包含 xargs、env、sh、python 等可以再执行任意命令的程序，也不包含 sed、awk 等可写文件或执行命令的程序
+var defaultShellCommands = map[string]*model.ShellCommandRule{
+	"ls":        {ForbiddenFlags: lsRecursiveFlags},
+	"cat":       {},
+	"head":      {},
+	"tail":      {},
+	"wc":        {ForbiddenFlags: []string{filesFromFlag}},
+	"grep":      {ForbiddenFlags: grepRecursiveFlags},
+	"egrep":     {ForbiddenFlags: grepRecursiveFlags},
+	"fgrep":     {ForbiddenFlags: grepRecursiveFlags},
+	"cut":       {},
+	"tr":        {},
+	"nl":        {},
+	"tac":       {},
+	"echo":      {},
+	"pwd":       {},
+	"whoami":    {},
+	"id":        {},
+	"uname":     {},
+	"uptime":    {},
+	"df":        {},
+	"du":        {ForbiddenFlags: []string{filesFromFlag}},
+	"free":      {},
+	"ps":        {},
+	"stat":      {},
+	"file":      {},
+	"which":     {},
+	"diff":      {ForbiddenFlags: diffRecursiveFlags},
+	"basename":  {},
+	"dirname":   {},
+	"realpath":  {},
+	"md5sum":    {},
+	"sha256sum": {},
+	"date":      {ForbiddenFlags: []string{"-s", "--set*"}},
+	"sort":      {ForbiddenFlags: []string{"-o", "--output*", "--compress-program*", filesFromFlag}},
+	"find": {ForbiddenFlags: []string{
+		"-delete", "-exec", "-execdir", "-ok", "-okdir", "-fls", "-fprint", "-fprint0", "-fprintf",
+	}},
+}
+
+// commandRule 编译后的单个可执行文件规则
+type commandRule struct {
+	paths           []string
+	allowedFlags    []string
+	forbiddenFlags  []string
+	argPatterns     []*regexp.Regexp
+	denyArgPatterns []*regexp.Regexp
+	maxArgs         int
+}
+
+// shellPolicy 编译后的命令策略，names 按命令名匹配，paths 按解析后的绝对路径匹配
+type shellPolicy struct {
+	mode      string
+	maxPipes  int
+	exec      string
+	shell     string             // exec 为 shell 时使用的 shell 路径
+	lang      syntax.LangVariant // 校验命令时使用的语法，与实际执行命令的方式一致
+	roots     []string           // 解析符号链接后的根目录
+	workDir   string
+	denyPaths []pathGlob
+	sandbox   *model.SandboxConfig
+	names     map[string]*commandRule
+	paths     map[string]*commandRule
+}
+
+var (
+	policyOnce sync.Once
+	policy     *shellPolicy
+)
+
+// getShellPolicy 首次使用时根据 shell 配置编译策略
+func getShellPolicy() *shellPolicy {
+	policyOnce.Do(func() {
+		policy = newShellPolicy(consts.Config.Shell)
+	})
+	return policy
+}
+
+func newShellPolicy(cfg *model.ShellConfig) *shellPolicy {
+	p := &shellPolicy{
+		mode:     ShellModeAllowlist,
+		maxPipes: defaultMaxPipes,
+		exec:     ShellExecDirect,
+		lang:     syntax.LangPOSIX,
+		names:    map[string]*commandRule{},
+		paths:    map[string]*commandRule{},
+	}
+	commands := defaultShellCommands
+	denyPaths := defaultDenyPaths
+	if cfg != nil {
+		switch strings.ToLower(cfg.Mode) {
+		case "", ShellModeAllowlist:
+		case ShellModeBlacklist:
+			p.mode = ShellModeBlacklist
+		default:
+			consts.Logger.Warningf(consts.Ctx, "未知的 shell.mode %s，使用 allowlist", cfg.Mode)
+		}
+		if cfg.MaxPipes > 0 {
+			p.maxPipes = cfg.MaxPipes
+		}
+		switch strings.ToLower(cfg.Exec) {
+		case "", ShellExecDirect:
+		case ShellExecShell:
+			if p.shell = detectShell(cfg.Path); p.shell != "" {
+				p.exec = ShellExecShell
+				p.lang = syntax.LangBash
+			}
+		default:
+			consts.Logger.Warningf(consts.Ctx, "未知的 shell.exec %s，使用 direct", cfg.Exec)
+		}
+		if len(cfg.Commands) > 0 {
+			commands = cfg.Commands
+		}
+		if len(cfg.DenyPaths) > 0 {
+			denyPaths = cfg.DenyPaths
+		}
+		for _, root := range cfg.Roots {
+			resolved, err := filepath.Abs(root)
+			if err == nil {
+				resolved, err = filepath.EvalSymlinks(resolved)
+			}
+			if err != nil {
+				consts.Logger.Warningf(consts.Ctx, "shell.roots 中的 %s 无效，已忽略: %v", root, err)
+				continue
+			}
+			p.roots = append(p.roots, resolved)
+		}
+		p.workDir = cfg.WorkDir
+		if p.workDir == "" && len(p.roots) > 0 {
+			p.workDir = p.roots[0]
+		}
+		p.sandbox = cfg.Sandbox
+		if p.sandbox != nil && p.sandbox.Enabled && !sandboxSupported {
+			consts.Logger.Warning(consts.Ctx, "shell.sandbox 仅支持 Linux，RunSafeShellCommand 将拒绝执行命令")
+		}
+	}
+	p.denyPaths = compilePathGlobs(denyPaths)
+	for key, item := range commands {
+		if item == nil {
+			item = &model.ShellCommandRule{}
+		}
+		rule := compileCommandRule(key, item)
+		if filepath.IsAbs(key) {
+			// 配置的路径本身可能是符号链接（如 /bin -> /usr/bin），统一按实际路径匹配
+			if resolved, err := filepath.EvalSymlinks(key); err == nil {
+				key = resolved
+			}
+			p.paths[key] = rule
+		} else {
+			p.names[key] = rule
+		}
+	}
+	return p
+}
+
+// detectShell 返回配置的 shell，未配置时按 shellCandidates 顺序在 PATH 中查找，找不到或不在 shellDialects 中时退回 direct 执行
+func detectShell(configured string) string {
+	candidates := shellCandidates
+	if configured != "" {
+		candidates = []string{configured}
+	}
+	for _, name := range candidates {
+		file, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+		resolved, err := filepath.EvalSymlinks(file)
+		if err != nil {
+			continue
+		}
+		if !shellDialects[filepath.Base(resolved)] {
+			consts.Logger.Warningf(consts.Ctx, "不支持的 shell %s（%s），命令校验无法覆盖其展开规则，改为 direct 执行", file, resolved)
+			return ""
+		}
+		return file
+	}
+	consts.Logger.Warningf(consts.Ctx, "未找到可用的 shell（%s），改为 direct 执行", strings.Join(candidates, "、"))
+	return ""
+}
+
+func compileCommandRule(key string, item *model.ShellCommandRule) *commandRule {
+	rule := &commandRule{
+		paths:          item.Paths,
+		allowedFlags:   item.AllowedFlags,
+		forbiddenFlags: item.ForbiddenFlags,
+		maxArgs:        item.MaxArgs,
+	}
+	compile := func(exprs []string) (list []*regexp.Regexp) {
+		for _, expr := range exprs {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				consts.Logger.Warningf(consts.Ctx, "shell.commands.%s 中的正则 %s 无效，已忽略: %v", key, expr, err)
+				continue
+			}
+			list = append(list, re)
+		}
+		return
+	}
+	rule.argPatterns = compile(item.ArgPatterns)
+	rule.denyArgPatterns = compile(item.DenyArgPatterns)
+	return rule
+}
+
+// validate 按白名单校验管道中的每一段命令，argv[0] 为可执行文件
+func (p *shellPolicy) validate(pipeline []shellStage) error {
+	if len(pipeline)-1 > p.maxPipes {
+		return fmt.Errorf("管道分段过多：最多允许 %d 个管道", p.maxPipes)
+	}
+	for _, stage := range pipeline {
+		argv := stage.argv
+		if len(argv) == 0 {
+			return errors.New("无效的空管道分段")
+		}
+		rule, err := p.lookup(argv[0])
+		if err != nil {
+			return err
+		}
+		if err = rule.check(filepath.Base(argv[0]), argv[1:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookup 解析可执行文件的实际路径并查找规则。
+// 按名称配置的命令只允许 PATH 中解析到的那一个文件（或 paths 中声明的路径），避免通过同名文件或绝对路径绕过
+func (p *shellPolicy) lookup(name string) (rule *commandRule, err error) {
+	if strings.Contains(name, "/") && !filepath.IsAbs(name) {
+		return nil, errors.New("可执行文件必须为命令名或绝对路径: " + name)
+	}
+	resolved, err := resolveExecutable(name)
+	if err != nil {
+		return nil, errors.New("无法解析可执行文件: " + name)
+	}
+	if rule = p.paths[resolved]; rule != nil {
+		return
+	}
+	base := filepath.Base(name)
+	if rule = p.names[base]; rule == nil {
+		return nil, errors.New("命令不在白名单中: " + base)
+	}
+	if len(rule.paths) > 0 {
+		for _, pattern := range rule.paths {
+			if ok, _ := path.Match(pattern, resolved); ok {
+				return
+			}
+		}
+		return nil, fmt.Errorf("命令 %s 的实际路径 %s 不在允许范围内", base, resolved)
+	}
+	expected, err := resolveExecutable(base)
+	if err != nil || expected != resolved {
+		return nil, fmt.Errorf("命令 %s 的实际路径 %s 不在允许范围内", base, resolved)
+	}
+	return rule, nil
+}
+
+// resolveExecutable 按 PATH 查找命令并解析符号链接
+func resolveExecutable(name string) (resolved string, err error) {
+	file := name
+	if !strings.Contains(name, "/") {
+		if file, err = exec.LookPath(name); err != nil {
+			return
+		}
+	}
+	if file, err = filepath.Abs(file); err != nil {
+		return
+	}
+	return filepath.EvalSymlinks(file)
+}
+
+// check 校验参数。以 - 开头的参数视为选项（-- 之后不再识别选项），其余参数按 argPatterns 校验
+func (r *commandRule) check(name string, args []string) error {
+	if r.maxArgs > 0 && len(args) > r.maxArgs {
+		return fmt.Errorf("命令 %s 的参数过多：最多允许 %d 个", name, r.maxArgs)
+	}
+	endOfFlags := false
+	for _, arg := range args {
+		for _, re := range r.denyArgPatterns {
+			if re.MatchString(arg) {
+				return fmt.Errorf("命令 %s 的参数 %s 被禁止", name, arg)
+			}
+		}
+		if !endOfFlags && arg == "--" {
+			endOfFlags = true
+			continue
+		}
+		if !endOfFlags && len(arg) > 1 && arg[0] == '-' {
+			if err := r.checkFlag(name, arg); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(r.argPatterns) > 0 && !matchAnyRegexp(r.argPatterns, arg) {
+			return fmt.Errorf("命令 %s 的参数 %s 不符合允许的格式", name, arg)
+		}
+	}
+	return nil
+}
+
+// checkFlag 校验单个选项。--name=value 只取名称；-abc 同时按整体和拆分后的 -a -b -c 匹配，
+// 以兼顾 find -delete 这类单横线长选项与 rm -rf 这类合并的短选项
+func (r *commandRule) checkFlag(name, arg string) error {
+	flag := arg
+	var shorts []string
+	if strings.HasPrefix(arg, "--") {
+		if i := strings.Index(arg, "="); i > 0 {
+			flag = arg[:i]
+		}
+	} else if len(arg) > 2 {
+		for _, c := range arg[1:] {
+			shorts = append(shorts, "-"+string(c))
+		}
+	}
+	for _, candidate := range append([]string{flag}, shorts...) {
+		if matchAnyGlob(r.forbiddenFlags, candidate) {
+			return fmt.Errorf("命令 %s 的选项 %s 被禁止", name, arg)
+		}
+	}
+	// getopt 允许长选项缩写（如 --out 等同于 --output），缩写同样视为被禁止
+	if strings.HasPrefix(flag, "--") && len(flag) > 2 {
+		for _, pattern := range r.forbiddenFlags {
+			if strings.HasPrefix(pattern, "--") && strings.HasPrefix(strings.TrimRight(pattern, "*"), flag) {
+				return fmt.Errorf("命令 %s 的选项 %s 被禁止", name, arg)
+			}
+		}
+	}
+	if len(r.allowedFlags) == 0 || matchAnyGlob(r.allowedFlags, flag) {
+		return nil
+	}
+	if len(shorts) > 0 {
+		allowed := true
+		for _, short := range shorts {
+			if !matchAnyGlob(r.allowedFlags, short) {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("命令 %s 的选项 %s 不在允许范围内", name, arg)
+}
+
+func matchAnyGlob(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAnyRegexp(list []*regexp.Regexp, s string) bool {
+	for _, re := range list {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
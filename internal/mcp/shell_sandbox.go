@@ -0,0 +1,93 @@
+package mcp
+
+import (
+	"ai-mcp/internal/auth"
+	"ai-mcp/internal/model"
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// 沙箱初始化进程通过该环境变量接收执行参数
+const sandboxSpecEnv = "AI_MCP_SANDBOX_SPEC"
+
+// 沙箱内始终只读挂载的系统目录，不存在的目录会被跳过
+var sandboxSystemDirs = []string{"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/libx32", "/etc"}
+
+// sandboxSpec 传给沙箱初始化进程的执行参数
+type sandboxSpec struct {
+	model.SandboxConfig
+	Root     string         `json:"root"`     // 作为新根目录挂载点的临时目录
+	Dir      string         `json:"dir"`      // 工作目录
+	Shell    string         `json:"shell"`    // 非空时交给该 shell 执行 Command，否则直接执行 Pipeline
+	Command  string         `json:"command"`  // 原始命令
+	Pipeline []sandboxStage `json:"pipeline"` // 解析后的管道
+}
+
+type sandboxStage struct {
+	Argv []string `json:"argv"`
+	Dups [][2]int `json:"dups"`
+}
+
+func (spec *sandboxSpec) stages() (pipeline []shellStage) {
+	for _, stage := range spec.Pipeline {
+		item := shellStage{argv: stage.Argv}
+		for _, dup := range stage.Dups {
+			item.dups = append(item.dups, fdDup{fd: dup[0], target: dup[1]})
+		}
+		pipeline = append(pipeline, item)
+	}
+	return
+}
+
+// RunSandboxInit 当前进程由 RunSafeShellCommand 作为沙箱初始化进程启动时，搭建沙箱并执行命令后返回退出码，否则 ok 为 false
+func (s *sMcpTool) RunSandboxInit() (code int, ok bool) {
+	data, ok := os.LookupEnv(sandboxSpecEnv)
+	if !ok {
+		return
+	}
+	return runSandboxInit(data), true
+}
+
+// sandboxFor 返回调用方使用的沙箱配置，授权配置中的 shellSandbox 优先于 shell.sandbox，未启用时返回 nil
+func (p *shellPolicy) sandboxFor(ctx context.Context) *model.SandboxConfig {
+	cfg := p.sandbox
+	if profile := auth.Auth.Profile(ctx); profile != nil && profile.ShellSandbox != nil {
+		cfg = profile.ShellSandbox
+	}
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	return cfg
+}
+
+// newSandboxSpec 组装沙箱执行参数：系统目录、roots（未配置时为工作目录）与 binds 只读挂载
+func (p *shellPolicy) newSandboxSpec(cfg *model.SandboxConfig, command, dir string, pipeline []shellStage) *sandboxSpec {
+	spec := &sandboxSpec{SandboxConfig: *cfg, Dir: dir, Command: command}
+	if p.exec == ShellExecShell {
+		spec.Shell = p.shell
+	}
+	for _, stage := range pipeline {
+		item := sandboxStage{Argv: stage.argv}
+		for _, dup := range stage.dups {
+			item.Dups = append(item.Dups, [2]int{dup.fd, dup.target})
+		}
+		spec.Pipeline = append(spec.Pipeline, item)
+	}
+
+	binds := append([]string{}, sandboxSystemDirs...)
+	if len(p.roots) > 0 {
+		binds = append(binds, p.roots...)
+	} else {
+		binds = append(binds, dir)
+	}
+	for _, bind := range cfg.Binds {
+		if resolved, err := filepath.EvalSymlinks(bind); err == nil {
+			if resolved, err = filepath.Abs(resolved); err == nil {
+				binds = append(binds, resolved)
+			}
+		}
+	}
+	spec.Binds = binds
+	return spec
+}
@@ -0,0 +1,259 @@
+//go:build linux
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const sandboxSupported = true
+
+// 沙箱内可用的设备文件
+var sandboxDevices = []string{"null", "zero", "random", "urandom"}
+
+// 沙箱内私有 /tmp 的大小
+const sandboxTmpSize = "64m"
+
+// runSandboxed 以沙箱初始化进程重新启动当前程序：新的 user/mount/PID/network 命名空间中，
+// 当前用户映射为命名空间内的 root 以便挂载，初始化进程搭建只读根目录、设置 rlimit 后再执行命令
+func runSandboxed(ctx context.Context, spec *sandboxSpec, stdout, stderr io.Writer) (err error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	if spec.Root, err = os.MkdirTemp("", "ai-mcp-sandbox-"); err != nil {
+		return
+	}
+	defer func() {
+		_ = os.Remove(spec.Root)
+	}()
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return
+	}
+
+	// 保留原始参数，初始化进程加载与服务相同的配置文件
+	cmd := exec.CommandContext(ctx, exe, os.Args[1:]...)
+	prepareCommand(cmd, "")
+	cmd.Env = append(os.Environ(), sandboxSpecEnv+"="+string(data))
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.SysProcAttr.Cloneflags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	if !spec.Network {
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+	cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}}
+	cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+	cmd.SysProcAttr.GidMappingsEnableSetgroups = false
+	// 初始化进程是 PID 命名空间的 1 号进程，超时或取消时结束它，命名空间内的所有进程随之结束
+	if err = cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			err = fmt.Errorf("沙箱启动失败（需要内核允许非特权用户命名空间）: %w", err)
+		}
+	}
+	return
+}
+
+// runSandboxInit 沙箱初始化进程：搭建隔离环境后执行命令，返回最后一个进程的退出码
+func runSandboxInit(data string) int {
+	_ = os.Unsetenv(sandboxSpecEnv)
+	spec := &sandboxSpec{}
+	if err := json.Unmarshal([]byte(data), spec); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		return 125
+	}
+	if err := setupSandbox(spec); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		return 125
+	}
+
+	var err error
+	if spec.Shell != "" {
+		err = runShell(context.Background(), spec.Shell, spec.Command, spec.Dir, os.Stdout, os.Stderr)
+	} else {
+		err = runPipeline(context.Background(), spec.stages(), spec.Dir, os.Stdout, os.Stderr)
+	}
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// 与 shell 一致，被信号结束时退出码为 128 + 信号值（如超出 CPU 时间的 SIGXCPU）
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return exitErr.ExitCode()
+	}
+	fmt.Fprintf(os.Stderr, "%v\n", err)
+	return 127
+}
+
+// setupSandbox 在新的挂载命名空间中以 tmpfs 为根目录，只读挂载允许的目录，提供私有 /tmp、最小 /dev 与新的 /proc，
+// 切换根目录后设置 rlimit、清空能力边界集并开启 no_new_privs
+func setupSandbox(spec *sandboxSpec) error {
+	// 挂载变更不传播回宿主机
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("mount private: %w", err)
+	}
+	root := spec.Root
+	if err := unix.Mount("tmpfs", root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size=1m,mode=0755"); err != nil {
+		return fmt.Errorf("mount root: %w", err)
+	}
+
+	tmp := filepath.Join(root, "tmp")
+	if err := os.MkdirAll(tmp, 0o755); err != nil {
+		return err
+	}
+	if err := unix.Mount("tmpfs", tmp, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size="+sandboxTmpSize+",mode=1777"); err != nil {
+		return fmt.Errorf("mount /tmp: %w", err)
+	}
+	dev := filepath.Join(root, "dev")
+	if err := os.MkdirAll(dev, 0o755); err != nil {
+		return err
+	}
+	if err := unix.Mount("tmpfs", dev, "tmpfs", unix.MS_NOSUID|unix.MS_NOEXEC, "size=64k,mode=0755"); err != nil {
+		return fmt.Errorf("mount /dev: %w", err)
+	}
+	for _, name := range sandboxDevices {
+		if err := bindMount("/dev/"+name, filepath.Join(dev, name), false); err != nil {
+			return err
+		}
+	}
+
+	// 先挂载上级目录，避免覆盖已挂载的子目录
+	binds := append([]string{}, spec.Binds...)
+	sort.Strings(binds)
+	for _, src := range binds {
+		if err := bindMount(src, filepath.Join(root, src), true); err != nil {
+			return err
+		}
+	}
+
+	proc := filepath.Join(root, "proc")
+	if err := os.MkdirAll(proc, 0o555); err != nil {
+		return err
+	}
+	// 部分容器环境不允许挂载 proc，此时沙箱内没有 /proc
+	_ = unix.Mount("proc", proc, "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "")
+
+	oldRoot := filepath.Join(root, ".oldroot")
+	if err := os.MkdirAll(oldRoot, 0o700); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(root, oldRoot); err != nil {
+		return fmt.Errorf("pivot_root: %w", err)
+	}
+	if err := unix.Chdir("/"); err != nil {
+		return err
+	}
+	if err := unix.Unmount("/.oldroot", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("umount old root: %w", err)
+	}
+	_ = os.Remove("/.oldroot")
+	if err := unix.Mount("tmpfs", "/", "tmpfs", unix.MS_REMOUNT|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("remount root read-only: %w", err)
+	}
+	if err := unix.Chdir(spec.Dir); err != nil {
+		return fmt.Errorf("chdir %s: %w", spec.Dir, err)
+	}
+	_ = unix.Sethostname([]byte("sandbox"))
+
+	limits := []struct {
+		resource int
+		value    uint64
+	}{
+		{unix.RLIMIT_CPU, spec.CpuSeconds},
+		{unix.RLIMIT_AS, spec.MemoryMB << 20},
+		{unix.RLIMIT_NPROC, spec.MaxProcesses},
+		{unix.RLIMIT_FSIZE, spec.FileSizeMB << 20},
+	}
+	for _, limit := range limits {
+		if limit.value == 0 {
+			continue
+		}
+		if err := unix.Setrlimit(limit.resource, &unix.Rlimit{Cur: limit.value, Max: limit.value}); err != nil {
+			return fmt.Errorf("setrlimit %d: %w", limit.resource, err)
+		}
+	}
+
+	// 命令以命名空间内的 root 运行，清空能力边界集后 execve 不再获得任何能力，无法重新挂载或卸载只读目录
+	for c := 0; ; c++ {
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(c), 0, 0, 0); err != nil {
+			if errors.Is(err, unix.EINVAL) {
+				break
+			}
+			return fmt.Errorf("drop capability %d: %w", c, err)
+		}
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("no_new_privs: %w", err)
+	}
+	return nil
+}
+
+// bindMount 把 src 挂载到 dst，不存在的 src 会被跳过，符号链接（如 /bin -> usr/bin）原样复制
+func bindMount(src, dst string, readonly bool) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, linkErr := os.Readlink(src)
+		if linkErr != nil {
+			return linkErr
+		}
+		return os.Symlink(target, dst)
+	case info.IsDir():
+		err = os.MkdirAll(dst, 0o755)
+	default:
+		var f *os.File
+		if f, err = os.OpenFile(dst, os.O_CREATE|os.O_WRONLY, 0o644); err == nil {
+			err = f.Close()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err = unix.Mount(src, dst, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind %s: %w", src, err)
+	}
+	if !readonly {
+		return nil
+	}
+	// 优先递归设置只读（Linux 5.12+），否则按原挂载的 nosuid/nodev/noexec 等标志重新挂载为只读
+	attr := &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY | unix.MOUNT_ATTR_NOSUID | unix.MOUNT_ATTR_NODEV}
+	if err = unix.MountSetattr(-1, dst, unix.AT_RECURSIVE, attr); err == nil {
+		return nil
+	}
+	var st unix.Statfs_t
+	if err = unix.Statfs(dst, &st); err != nil {
+		return err
+	}
+	flags := uintptr(st.Flags) & (unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC | unix.MS_NOATIME | unix.MS_NODIRATIME)
+	if st.Flags&unix.ST_RELATIME != 0 {
+		flags |= unix.MS_RELATIME
+	}
+	if err = unix.Mount("", dst, "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV|flags, ""); err != nil {
+		return fmt.Errorf("remount %s read-only: %w", src, err)
+	}
+	return nil
+}
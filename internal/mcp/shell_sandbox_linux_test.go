@@ -0,0 +1,177 @@
+//go:build linux
+
+package mcp
+
+import (
+	"ai-mcp/internal/model"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runInSandbox 在沙箱中直接执行 argv，返回标准输出、标准错误与退出码；dir 为工作目录，同时作为唯一的根目录挂载
+func runInSandbox(t *testing.T, cfg model.SandboxConfig, dir string, argv ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	cfg.Enabled = true
+	p := newShellPolicy(&model.ShellConfig{Roots: []string{dir}})
+	spec := p.newSandboxSpec(&cfg, strings.Join(argv, " "), p.roots[0], []shellStage{{argv: argv}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var outBuf, errBuf bytes.Buffer
+	err := runSandboxed(ctx, spec, &outBuf, &errBuf)
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		code = exitErr.ExitCode()
+	default:
+		t.Fatalf("runSandboxed: %v", err)
+	}
+	return outBuf.String(), errBuf.String(), code
+}
+
+// requireSandbox 当前环境不允许非特权用户命名空间或挂载时跳过测试
+func requireSandbox(t *testing.T) {
+	t.Helper()
+	if data, err := os.ReadFile("/proc/sys/user/max_user_namespaces"); err == nil && strings.TrimSpace(string(data)) == "0" {
+		t.Skip("user.max_user_namespaces 为 0，不支持用户命名空间")
+	}
+	dir := t.TempDir()
+	p := newShellPolicy(&model.ShellConfig{Roots: []string{dir}})
+	spec := p.newSandboxSpec(&model.SandboxConfig{Enabled: true}, "true", p.roots[0], []shellStage{{argv: []string{"true"}}})
+	var stderr bytes.Buffer
+	err := runSandboxed(context.Background(), spec, &bytes.Buffer{}, &stderr)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 125 {
+		t.Skipf("当前环境无法搭建沙箱: %s", strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		t.Skipf("当前环境不支持沙箱: %v", err)
+	}
+}
+
+func TestSandboxFilesystem(t *testing.T) {
+	requireSandbox(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "data.txt"), []byte("visible"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	hostTmp, err := os.CreateTemp("", "ai-mcp-host-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = hostTmp.Close()
+	t.Cleanup(func() {
+		_ = os.Remove(hostTmp.Name())
+	})
+
+	// 工作目录只读挂载，内容可读
+	if out, stderr, code := runInSandbox(t, model.SandboxConfig{}, dir, "cat", "data.txt"); code != 0 || out != "visible" {
+		t.Errorf("读取工作目录中的文件：输出 %q，退出码 %d，%s", out, code, stderr)
+	}
+	// 根目录、系统目录与工作目录均不可写
+	for _, file := range []string{"/created", "/etc/created", filepath.Join(dir, "created")} {
+		if _, _, code := runInSandbox(t, model.SandboxConfig{}, dir, "touch", file); code == 0 {
+			t.Errorf("沙箱内写入 %s 成功，期望只读", file)
+		}
+		if _, err := os.Stat(file); err == nil {
+			_ = os.Remove(file)
+			t.Errorf("沙箱内的写入出现在宿主机上：%s", file)
+		}
+	}
+
+	// /tmp 为私有 tmpfs：可写、看不到宿主机的文件，写入的文件也不会出现在宿主机上
+	name := filepath.Base(hostTmp.Name())
+	if out, stderr, code := runInSandbox(t, model.SandboxConfig{}, dir, "ls", "-a", "/tmp"); code != 0 || strings.Contains(out, name) {
+		t.Errorf("沙箱内的 /tmp 应为私有目录：输出 %q，退出码 %d，%s", out, code, stderr)
+	}
+	private := filepath.Join(os.TempDir(), "ai-mcp-sandbox-private-"+name)
+	if _, stderr, code := runInSandbox(t, model.SandboxConfig{}, dir, "touch", private); code != 0 {
+		t.Errorf("沙箱内的 /tmp 应可写，退出码 %d，%s", code, stderr)
+	}
+	if _, err := os.Stat(private); err == nil {
+		_ = os.Remove(private)
+		t.Error("沙箱内写入 /tmp 的文件出现在宿主机上")
+	}
+}
+
+func TestSandboxNetwork(t *testing.T) {
+	requireSandbox(t)
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	t.Setenv(sandboxDialEnv, listener.Addr().String())
+
+	// 测试程序所在目录额外挂载，以便在沙箱内运行网络探测；探测程序启动时需要从工作目录加载配置文件
+	binds := []string{filepath.Dir(exe)}
+	dir := t.TempDir()
+	if err = os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("shell: {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, stderr, code := runInSandbox(t, model.SandboxConfig{Binds: binds}, dir, exe); code != sandboxDialFailed {
+		t.Errorf("默认不保留网络，沙箱内不应能连接宿主机上的 %s，退出码 %d，%s", listener.Addr(), code, stderr)
+	}
+	if _, stderr, code := runInSandbox(t, model.SandboxConfig{Binds: binds, Network: true}, dir, exe); code != 0 {
+		t.Errorf("network 为 true 时沙箱内应能连接宿主机，退出码 %d，%s", code, stderr)
+	}
+}
+
+func TestSandboxRlimits(t *testing.T) {
+	requireSandbox(t)
+	dir := t.TempDir()
+
+	tests := []struct {
+		name  string
+		cfg   model.SandboxConfig
+		argv  []string
+		codes []int // 期望的退出码，为空表示只要求失败
+	}{
+		// 超出 CPU 时间收到 SIGXCPU（128 + 24）；软硬上限相同，内核也可能直接发送 SIGKILL（128 + 9）
+		{name: "cpuSeconds", cfg: model.SandboxConfig{CpuSeconds: 1}, argv: []string{"sha256sum", "/dev/zero"}, codes: []int{152, 137}},
+		// 超出文件大小收到 SIGXFSZ，退出码为 128 + 25
+		{name: "fileSizeMB", cfg: model.SandboxConfig{FileSizeMB: 1}, argv: []string{"dd", "if=/dev/zero", "of=/tmp/big", "bs=1M", "count=2"}, codes: []int{153}},
+		// /dev/zero 没有换行，sort 需要把整行读入内存，超出地址空间上限后失败
+		{name: "memoryMB", cfg: model.SandboxConfig{MemoryMB: 64}, argv: []string{"sort", "/dev/zero"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := exec.LookPath(tt.argv[0]); err != nil {
+				t.Skipf("未找到 %s", tt.argv[0])
+			}
+			start := time.Now()
+			_, stderr, code := runInSandbox(t, tt.cfg, dir, tt.argv...)
+			if code == 0 || (len(tt.codes) > 0 && !slices.Contains(tt.codes, code)) {
+				t.Errorf("%s 退出码 %d，期望 rlimit 生效（%v），%s", strings.Join(tt.argv, " "), code, tt.codes, stderr)
+			}
+			if elapsed := time.Since(start); elapsed > 20*time.Second {
+				t.Errorf("rlimit 未及时生效，耗时 %s", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const sandboxSupported = false
+
+func runSandboxed(ctx context.Context, spec *sandboxSpec, stdout, stderr io.Writer) error {
+	return errors.New("沙箱仅支持 Linux")
+}
+
+func runSandboxInit(data string) int {
+	fmt.Fprintln(os.Stderr, "sandbox: 沙箱仅支持 Linux")
+	return 125
+}
@@ -0,0 +1,189 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Integer 将 mcp.WithNumber 声明的参数限定为整数
+func Integer() mcp.PropertyOption {
+	return func(schema map[string]any) {
+		schema["type"] = "integer"
+	}
+}
+
+// validating 按工具声明的 inputSchema 校验参数并转换为声明的类型后再调用工具
+// 兼容旧客户端的字符串形式：数字、布尔值可传字符串，数组可传 JSON 数组字符串
+func validating(schema mcp.ToolInputSchema, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := validateArguments(schema, request.GetArguments())
+		if err != nil {
+			return toolError(ErrCodeInvalidArgument, "%s", err.Error()), nil
+		}
+		request.Params.Arguments = args
+		return next(ctx, request)
+	}
+}
+
+// validateArguments 返回类型转换后的参数副本，未在 schema 中声明的参数原样保留
+func validateArguments(schema mcp.ToolInputSchema, args map[string]any) (map[string]any, error) {
+	out := make(map[string]any, len(args))
+	for key, value := range args {
+		out[key] = value
+	}
+	for _, name := range schema.Required {
+		if value, ok := out[name]; !ok || value == nil || value == "" {
+			return nil, fmt.Errorf("%s is required", name)
+		}
+	}
+	for name, raw := range schema.Properties {
+		property, _ := raw.(map[string]any)
+		value, ok := out[name]
+		if !ok || value == nil || property == nil {
+			continue
+		}
+		// 可选参数传空字符串视为未传，兼容旧客户端对可选参数统一传 ""
+		if value == "" && !slices.Contains(schema.Required, name) && property["type"] != "string" {
+			delete(out, name)
+			continue
+		}
+		converted, err := validateValue(name, property, value)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = converted
+	}
+	return out, nil
+}
+
+func validateValue(name string, property map[string]any, value any) (any, error) {
+	switch property["type"] {
+	case "integer", "number":
+		number, err := toNumber(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", name)
+		}
+		if property["type"] == "integer" && number != math.Trunc(number) {
+			return nil, fmt.Errorf("%s must be an integer", name)
+		}
+		if min, ok := property["minimum"].(float64); ok && number < min {
+			return nil, fmt.Errorf("%s must be >= %v", name, min)
+		}
+		if max, ok := property["maximum"].(float64); ok && number > max {
+			return nil, fmt.Errorf("%s must be <= %v", name, max)
+		}
+		if _, err = checkEnum(name, property, gconv.String(number)); err != nil {
+			return nil, err
+		}
+		if property["type"] == "integer" {
+			return int(number), nil
+		}
+		return number, nil
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, nil
+			}
+		}
+		return nil, fmt.Errorf("%s must be a boolean", name)
+	case "array":
+		list, ok := value.([]any)
+		if str, isString := value.(string); isString {
+			if err := gjson.Unmarshal([]byte(str), &list); err != nil {
+				return nil, fmt.Errorf("%s must be an array or a JSON array string", name)
+			}
+			ok = true
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s must be an array", name)
+		}
+		if min, ok := property["minItems"].(int); ok && len(list) < min {
+			return nil, fmt.Errorf("%s must contain at least %d items", name, min)
+		}
+		if max, ok := property["maxItems"].(int); ok && len(list) > max {
+			return nil, fmt.Errorf("%s must contain at most %d items", name, max)
+		}
+		if items, ok := property["items"].(map[string]any); ok {
+			for i, item := range list {
+				converted, err := validateValue(fmt.Sprintf("%s[%d]", name, i), items, item)
+				if err != nil {
+					return nil, err
+				}
+				list[i] = converted
+			}
+		}
+		return list, nil
+	case "string":
+		var str string
+		switch v := value.(type) {
+		case string:
+			str = v
+		case float64, bool:
+			// 数字、布尔值按字符串处理，兼容将字符串参数传成 JSON 原生类型的客户端
+			str = gconv.String(v)
+		default:
+			return nil, fmt.Errorf("%s must be a string", name)
+		}
+		if min, ok := property["minLength"].(int); ok && utf8.RuneCountInString(str) < min {
+			return nil, fmt.Errorf("%s must be at least %d characters", name, min)
+		}
+		if max, ok := property["maxLength"].(int); ok && utf8.RuneCountInString(str) > max {
+			return nil, fmt.Errorf("%s must be at most %d characters", name, max)
+		}
+		if pattern, ok := property["pattern"].(string); ok {
+			if matched, err := regexp.MatchString(pattern, str); err != nil || !matched {
+				return nil, fmt.Errorf("%s must match %s", name, pattern)
+			}
+		}
+		return checkEnum(name, property, str)
+	}
+	return value, nil
+}
+
+func toNumber(value any) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+	return 0, fmt.Errorf("not a number: %v", value)
+}
+
+// 错误信息中列出的枚举值上限，超出时只提示不支持，完整列表见 inputSchema
+const maxEnumInMessage = 10
+
+// checkEnum 校验枚举值，大小写不同时返回声明的写法，兼容传入小写 Redis 命令等旧客户端
+func checkEnum(name string, property map[string]any, value string) (string, error) {
+	values, ok := property["enum"].([]string)
+	if !ok || len(values) == 0 || slices.Contains(values, value) {
+		return value, nil
+	}
+	for _, item := range values {
+		if strings.EqualFold(item, value) {
+			return item, nil
+		}
+	}
+	if len(values) > maxEnumInMessage {
+		return "", fmt.Errorf("%s %q is not supported", name, value)
+	}
+	return "", fmt.Errorf("%s must be one of %s", name, strings.Join(values, ", "))
+}
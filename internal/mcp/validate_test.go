@@ -0,0 +1,118 @@
+package mcp
+
+import (
+	"ai-mcp/internal/model"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestValidateArguments(t *testing.T) {
+	schema := mcp.NewTool("test",
+		mcp.WithNumber("count", Integer(), mcp.Required(), mcp.Min(1), mcp.Max(10)),
+		mcp.WithNumber("ratio", mcp.Min(0), mcp.Max(1)),
+		mcp.WithBoolean("enabled"),
+		mcp.WithArray("tags", mcp.WithStringItems(), mcp.MaxItems(2)),
+		mcp.WithString("mode", mcp.Enum("fast", "safe")),
+		mcp.WithString("name", mcp.MaxLength(5)),
+	).InputSchema
+
+	tests := []struct {
+		name    string
+		args    map[string]any
+		want    map[string]any
+		wantErr bool
+	}{
+		{name: "原生类型", args: map[string]any{"count": float64(3), "ratio": 0.5, "enabled": true}, want: map[string]any{"count": 3, "ratio": 0.5, "enabled": true}},
+		{name: "字符串形式的整数", args: map[string]any{"count": "3"}, want: map[string]any{"count": 3}},
+		{name: "带空白的字符串数字", args: map[string]any{"count": " 7 ", "ratio": "0.25"}, want: map[string]any{"count": 7, "ratio": 0.25}},
+		{name: "字符串形式的布尔值", args: map[string]any{"count": 1, "enabled": "false"}, want: map[string]any{"count": 1, "enabled": false}},
+		{name: "JSON 数组字符串", args: map[string]any{"count": 1, "tags": `["a","b"]`}, want: map[string]any{"count": 1, "tags": []any{"a", "b"}}},
+		{name: "数字传给字符串参数", args: map[string]any{"count": 1, "name": float64(42)}, want: map[string]any{"count": 1, "name": "42"}},
+		{name: "可选参数传空字符串视为未传", args: map[string]any{"count": 1, "ratio": "", "enabled": ""}, want: map[string]any{"count": 1}},
+		{name: "枚举值大小写不同时转换为声明的写法", args: map[string]any{"count": 1, "mode": "FAST"}, want: map[string]any{"count": 1, "mode": "fast"}},
+		{name: "未声明的参数原样保留", args: map[string]any{"count": 1, "extra": map[string]any{"k": "v"}}, want: map[string]any{"count": 1, "extra": map[string]any{"k": "v"}}},
+		{name: "缺少必填参数", args: map[string]any{"ratio": 0.5}, wantErr: true},
+		{name: "必填参数为空字符串", args: map[string]any{"count": ""}, wantErr: true},
+		{name: "必填参数为 null", args: map[string]any{"count": nil}, wantErr: true},
+		{name: "非数字字符串", args: map[string]any{"count": "three"}, wantErr: true},
+		{name: "整数参数传小数", args: map[string]any{"count": "2.5"}, wantErr: true},
+		{name: "小于 minimum", args: map[string]any{"count": 0}, wantErr: true},
+		{name: "大于 maximum", args: map[string]any{"count": "11"}, wantErr: true},
+		{name: "number 大于 maximum", args: map[string]any{"count": 1, "ratio": 1.5}, wantErr: true},
+		{name: "无效的布尔值", args: map[string]any{"count": 1, "enabled": "yes"}, wantErr: true},
+		{name: "无效的 JSON 数组字符串", args: map[string]any{"count": 1, "tags": "a,b"}, wantErr: true},
+		{name: "超过 maxItems", args: map[string]any{"count": 1, "tags": []any{"a", "b", "c"}}, wantErr: true},
+		{name: "数组元素类型错误", args: map[string]any{"count": 1, "tags": []any{"a", []any{"b"}}}, wantErr: true},
+		{name: "不在枚举中", args: map[string]any{"count": 1, "mode": "slow"}, wantErr: true},
+		{name: "超过 maxLength", args: map[string]any{"count": 1, "name": "abcdef"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateArguments(schema, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateArguments(%v) err = %v, wantErr %t", tt.args, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateArguments(%v) = %#v, want %#v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+// 内置工具声明的参数约束
+func TestToolSchemas(t *testing.T) {
+	schemas := map[string]mcp.ToolInputSchema{}
+	for _, item := range McpHandler.GetList() {
+		schemas[item.Name] = McpHandler.NewTool(&item).InputSchema
+	}
+
+	tests := []struct {
+		tool    string
+		args    map[string]any
+		want    map[string]any
+		wantErr bool
+	}{
+		{tool: "TimestampToDateTime", args: map[string]any{"timestamp": "1700000000"}, want: map[string]any{"timestamp": 1700000000}},
+		{tool: "TimestampToDateTime", args: map[string]any{"timestamp": 0}, wantErr: true},
+		{tool: "NowTime", args: map[string]any{"timeZone": "Asia/Tokyo"}, want: map[string]any{"timeZone": "Asia/Tokyo"}},
+		{tool: "NowTime", args: map[string]any{"timeZone": "asia/shanghai"}, want: map[string]any{"timeZone": "Asia/Shanghai"}},
+		{tool: "NowTime", args: map[string]any{"timeZone": "Mars/Olympus"}, wantErr: true},
+		{tool: "NowTime", args: map[string]any{}, wantErr: true},
+		{tool: "ExecRedisCommand", args: map[string]any{"command": "hgetall", "args": `["user:1"]`}, want: map[string]any{"command": "HGETALL", "args": []any{"user:1"}}},
+		{tool: "ExecRedisCommand", args: map[string]any{"command": "FLUSHALL"}, wantErr: true},
+		{tool: "ExecRedisCommand", args: map[string]any{"command": "EXPIRE", "args": []any{"k", float64(60)}}, want: map[string]any{"command": "EXPIRE", "args": []any{"k", "60"}}},
+		{tool: "ExecRedisCommand", args: map[string]any{"command": "GET", "args": []any{map[string]any{"k": "v"}}}, wantErr: true},
+		{tool: "GetCalendarDays", args: map[string]any{"year": "2024", "month": "13"}, wantErr: true},
+		{tool: "RunSafeShellCommand", args: map[string]any{"command": "ls", "timeoutSeconds": "61"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			got, err := validateArguments(schemas[tt.tool], tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateArguments(%v) err = %v, wantErr %t", tt.args, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateArguments(%v) = %#v, want %#v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetNowTimeZone(t *testing.T) {
+	for _, timeZone := range timeZones {
+		out, err := callTool(McpTool.GetNowTime, context.Background(), "NowTime", map[string]any{"timeZone": timeZone})
+		if err != nil || out.IsError {
+			t.Fatalf("NowTime(%s) err = %v, result %+v", timeZone, err, out)
+		}
+		if got := out.StructuredContent.(model.NowTimeOutput).TimeZone; got != timeZone {
+			t.Errorf("NowTime(%s) timeZone = %s", timeZone, got)
+		}
+	}
+	out, _ := callTool(McpTool.GetNowTime, context.Background(), "NowTime", map[string]any{"timeZone": "Mars/Olympus"})
+	if !out.IsError {
+		t.Error("未知时区应返回错误")
+	}
+}
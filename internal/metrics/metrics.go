@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"ai-mcp/internal/consts"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// 工具调用结果
+const (
+	OutcomeSuccess   = "success"
+	OutcomeToolError = "tool-error"
+	OutcomePanic     = "panic"
+	OutcomeDenied    = "denied"
+	OutcomeThrottled = "throttled"
+	OutcomeTimeout   = "timeout"
+	OutcomeCancelled = "cancelled"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	toolCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ai_mcp_tool_calls_total",
+		Help: "Total number of MCP tool calls.",
+	}, []string{"tool", "outcome", "caller"})
+
+	toolDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ai_mcp_tool_call_duration_seconds",
+		Help:    "Duration of MCP tool calls in seconds.",
+		Buckets: []float64{.005, .01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"tool", "outcome", "caller"})
+
+	// ActiveSessions 当前已注册的 MCP 会话数（SSE 连接、Streamable HTTP 监听流）
+	ActiveSessions = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ai_mcp_active_sessions",
+		Help: "Number of active MCP sessions.",
+	})
+
+	// ShellProcesses 正在运行的 RunSafeShellCommand 进程数
+	ShellProcesses = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ai_mcp_shell_processes_running",
+		Help: "Number of shell commands currently running.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		toolCalls,
+		toolDuration,
+		ActiveSessions,
+		ShellProcesses,
+		&dbPoolCollector{},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// ObserveToolCall 记录一次工具调用
+func ObserveToolCall(tool, caller, outcome string, duration time.Duration) {
+	toolCalls.WithLabelValues(tool, outcome, caller).Inc()
+	toolDuration.WithLabelValues(tool, outcome, caller).Observe(duration.Seconds())
+}
+
+// Handler /metrics 接口
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+var (
+	dbPoolOpen  = prometheus.NewDesc("ai_mcp_db_pool_open_connections", "Number of established database connections.", []string{"group"}, nil)
+	dbPoolInUse = prometheus.NewDesc("ai_mcp_db_pool_in_use_connections", "Number of database connections currently in use.", []string{"group"}, nil)
+	dbPoolIdle  = prometheus.NewDesc("ai_mcp_db_pool_idle_connections", "Number of idle database connections.", []string{"group"}, nil)
+	dbPoolMax   = prometheus.NewDesc("ai_mcp_db_pool_max_open_connections", "Maximum number of open database connections.", []string{"group"}, nil)
+)
+
+// dbPoolCollector 采集时读取各数据库分组主节点的连接池状态
+type dbPoolCollector struct{}
+
+func (c *dbPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dbPoolOpen
+	ch <- dbPoolInUse
+	ch <- dbPoolIdle
+	ch <- dbPoolMax
+}
+
+func (c *dbPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, group := range consts.DbGroups() {
+		db, err := g.DB(group).Master()
+		if err != nil {
+			continue
+		}
+		stats := db.Stats()
+		ch <- prometheus.MustNewConstMetric(dbPoolOpen, prometheus.GaugeValue, float64(stats.OpenConnections), group)
+		ch <- prometheus.MustNewConstMetric(dbPoolInUse, prometheus.GaugeValue, float64(stats.InUse), group)
+		ch <- prometheus.MustNewConstMetric(dbPoolIdle, prometheus.GaugeValue, float64(stats.Idle), group)
+		ch <- prometheus.MustNewConstMetric(dbPoolMax, prometheus.GaugeValue, float64(stats.MaxOpenConnections), group)
+	}
+}
+
+// RegisterHooks 通过 MCP 会话注册/注销钩子统计活跃会话数
+func RegisterHooks(hooks *server.Hooks) {
+	hooks.AddOnRegisterSession(func(ctx context.Context, session server.ClientSession) {
+		ActiveSessions.Inc()
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		ActiveSessions.Dec()
+	})
+}
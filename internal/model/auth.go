@@ -0,0 +1,15 @@
+package model
+
+// Identity 调用方身份
+type Identity struct {
+	Name    string `json:"name"`    // 调用方名称（API Key 名称 / Token sub / 客户端证书 CN）
+	Method  string `json:"method"`  // 认证方式：api-key / bearer / tls / stdio / none
+	Profile string `json:"profile"` // 授权配置名称
+}
+
+func (i *Identity) String() string {
+	if i == nil {
+		return "unknown"
+	}
+	return i.Method + ":" + i.Name
+}
@@ -1,12 +1,166 @@
 package model
 
 type ConfigData struct {
-	McpServer *McpServerConfig `json:"mcp-server"`
-	DbConfig  *DbConfig        `json:"dbConfig"`
+	McpServer  *McpServerConfig        `json:"mcp-server"`
+	DbConfig   *DbConfig               `json:"dbConfig"`
+	Profiles   map[string]*ToolProfile `json:"profiles"`
+	Tools      *ToolsConfig            `json:"tools"`
+	Tracing    *TracingConfig          `json:"tracing"`
+	Audit      *AuditConfig            `json:"audit"`
+	Redaction  *RedactionConfig        `json:"redaction"`
+	Middleware *MiddlewareConfig       `json:"middleware"`
+	Shell      *ShellConfig            `json:"shell"`
 }
 
 type McpServerConfig struct {
-	Address string `json:"address"`
+	Address         string                `json:"address"`
+	AdminAddress    string                `json:"adminAddress"`    // 健康检查、就绪检查、版本与指标接口的独立监听地址，为空时与 MCP 服务共用监听（/metrics 需要认证）
+	Transport       string                `json:"transport"`       // 传输方式：stdio / sse / streamable-http / http，默认 sse
+	ShutdownTimeout int                   `json:"shutdownTimeout"` // 优雅关闭时等待进行中工具调用的时长（秒），默认 30
+	StreamableHttp  *StreamableHttpConfig `json:"streamableHttp"`
+	Auth            *AuthConfig           `json:"auth"`
+	Tls             *TlsConfig            `json:"tls"`
+}
+
+type TlsConfig struct {
+	Enabled        bool              `json:"enabled"`        // 是否启用 HTTPS
+	CertFile       string            `json:"certFile"`       // 服务端证书
+	KeyFile        string            `json:"keyFile"`        // 服务端私钥
+	ClientCaFile   string            `json:"clientCaFile"`   // 客户端证书 CA，配置后启用双向 TLS
+	ClientAuth     string            `json:"clientAuth"`     // 客户端证书校验方式：require（默认）/ optional
+	ClientProfiles map[string]string `json:"clientProfiles"` // 客户端证书 CN 到授权配置的映射
+}
+
+type StreamableHttpConfig struct {
+	Path              string `json:"path"`              // 服务路径，默认 /mcp
+	Stateless         bool   `json:"stateless"`         // 无状态模式，不分配会话 ID
+	HeartbeatInterval int    `json:"heartbeatInterval"` // GET 监听流心跳间隔（秒），0 表示不发送
+	SessionSecret     string `json:"sessionSecret"`     // 会话 ID 签名密钥，配置后服务重启仍接受已签发的会话 ID
+	SessionTTL        int    `json:"sessionTTL"`        // 会话 ID 自签发起的有效期（秒），默认 86400
+}
+
+type AuthConfig struct {
+	Enabled        bool         `json:"enabled"`        // 是否启用认证，未启用时所有 HTTP 请求均视为匿名调用方
+	ApiKeys        []AuthApiKey `json:"apiKeys"`        // 静态 API Key 列表
+	HmacSecret     string       `json:"hmacSecret"`     // HS256 签名 Bearer Token 的密钥，为空表示不接受 Token
+	DefaultProfile string       `json:"defaultProfile"` // 未指定授权配置的调用方（含匿名与 stdio）使用的配置，为空表示不限制
+}
+
+type AuthApiKey struct {
+	Name    string `json:"name"`    // 调用方名称
+	Key     string `json:"key"`     // API Key
+	Profile string `json:"profile"` // 授权配置名称，对应 profiles 节点
+}
+
+// ToolProfile 工具授权配置，allow/deny 支持工具名或 glob（如 Base64*），deny 优先
+type ToolProfile struct {
+	Allow        []string       `json:"allow"`        // 允许的工具，为空表示全部允许
+	Deny         []string       `json:"deny"`         // 禁止的工具
+	SqlReadonly  bool           `json:"sqlReadonly"`  // SQL_Actuator 仅允许只读语句
+	ShellSandbox *SandboxConfig `json:"shellSandbox"` // 该调用方执行 RunSafeShellCommand 时使用的沙箱，覆盖 shell.sandbox
+}
+
+// ToolsConfig 工具注册配置，enabled/disabled 支持工具名或 glob，disabled 优先
+type ToolsConfig struct {
+	Enabled   []string                       `json:"enabled"`   // 启用的工具，为空表示全部启用
+	Disabled  []string                       `json:"disabled"`  // 禁用的工具
+	Overrides map[string]*ToolOverrideConfig `json:"overrides"` // 按工具名覆盖配置
+}
+
+type ToolOverrideConfig struct {
+	Description string `json:"description"` // 覆盖工具描述
+}
+
+type TracingConfig struct {
+	Enabled     bool    `json:"enabled"`     // 是否启用链路追踪
+	Exporter    string  `json:"exporter"`    // 导出方式：otlp / stdout / file
+	Endpoint    string  `json:"endpoint"`    // OTLP HTTP 地址（host:port），为空时使用 OTEL_EXPORTER_OTLP_ENDPOINT
+	Insecure    bool    `json:"insecure"`    // OTLP 使用 HTTP 明文传输
+	File        string  `json:"file"`        // file 导出方式的输出文件
+	SampleRatio float64 `json:"sampleRatio"` // 采样比例 0-1，默认 1
+	ServiceName string  `json:"serviceName"` // 服务名，默认 ai-mcp
+}
+
+type AuditConfig struct {
+	Enabled           bool   `json:"enabled"`           // 是否启用审计日志
+	Path              string `json:"path"`              // 审计日志目录，默认 ./logs/audit
+	File              string `json:"file"`              // 文件名格式，默认 audit-{Y-m-d}.jsonl（按天切分）
+	RotateSize        string `json:"rotateSize"`        // 按大小切分，如 100MB，为空表示不按大小切分
+	RotateBackupLimit int    `json:"rotateBackupLimit"` // 按大小切分后保留的备份数，0 表示全部保留
+}
+
+// RedactionConfig 日志、审计与链路追踪中参数及内容的脱敏规则
+type RedactionConfig struct {
+	Mask            string              `json:"mask"`            // 遮蔽符，默认 ***
+	DisableDefaults bool                `json:"disableDefaults"` // 关闭内置的敏感参数名与内容正则
+	SensitiveKeys   []string            `json:"sensitiveKeys"`   // 参数名包含这些片段时整体遮蔽（不区分大小写）
+	Tools           map[string][]string `json:"tools"`           // 按工具遮蔽指定参数，* 表示全部参数
+	Patterns        []RedactionPattern  `json:"patterns"`        // 内容正则
+}
+
+type RedactionPattern struct {
+	Name    string `json:"name"`    // 规则名称
+	Regex   string `json:"regex"`   // 正则表达式
+	Replace string `json:"replace"` // 替换内容，支持 ${1} 引用分组，为空时整体替换为遮蔽符
+}
+
+// MiddlewareConfig 工具调用中间件配置
+type MiddlewareConfig struct {
+	Chain     []string         `json:"chain"`     // 中间件执行顺序，靠前的位于外层，为空时使用 recovery/logging/auth/rateLimit/timeout
+	Timeout   *TimeoutConfig   `json:"timeout"`   // timeout 中间件配置
+	RateLimit *RateLimitConfig `json:"rateLimit"` // rateLimit 中间件配置
+}
+
+type TimeoutConfig struct {
+	Default int            `json:"default"` // 工具调用超时时间（秒），默认 60，小于 0 表示不限制
+	Tools   map[string]int `json:"tools"`   // 按工具名覆盖超时时间（秒）
+}
+
+// RateLimitConfig 限流配置，顶层字段为全局限制，tools、callers 分别按工具名与调用方限制，多个维度同时生效
+type RateLimitConfig struct {
+	RateLimitRule
+	Tools   map[string]*RateLimitRule `json:"tools"`   // 按工具名限制
+	Callers map[string]*RateLimitRule `json:"callers"` // 按调用方限制，键为 method:name 或 name，* 表示其余每个调用方
+}
+
+type RateLimitRule struct {
+	Rate          float64 `json:"rate"`          // 每秒允许的调用次数，0 表示不限流
+	Burst         int     `json:"burst"`         // 允许的突发调用次数，默认与 rate 相同
+	MaxConcurrent int     `json:"maxConcurrent"` // 最大并发调用数，0 表示不限制
+}
+
+// ShellConfig RunSafeShellCommand 的命令策略
+type ShellConfig struct {
+	Mode      string                       `json:"mode"`      // 校验模式：allowlist（默认，仅允许 commands 中的可执行文件）/ blacklist（旧版黑名单）
+	MaxPipes  int                          `json:"maxPipes"`  // 最多允许的管道数，默认 3
+	Exec      string                       `json:"exec"`      // 执行方式：direct（默认，不经过 shell，按解析出的参数直接启动进程）/ shell（交给 path 指定的 shell 执行）
+	Path      string                       `json:"path"`      // exec 为 shell 时使用的 shell，为空时依次查找 sh、bash，仅支持 sh、bash、dash、ash、busybox
+	Roots     []string                     `json:"roots"`     // 允许的工作目录根目录，cwd 解析符号链接后必须位于其中之一，为空表示不限制
+	WorkDir   string                       `json:"workDir"`   // cwd 为空时使用的工作目录，为空时使用 roots 中的第一个，均未配置时使用服务进程的工作目录
+	DenyPaths []string                     `json:"denyPaths"` // 禁止读取的路径 glob（支持 ~ 与 **，命中目录时其下所有文件同样禁止），为空时使用内置列表，配置后整体替换内置列表
+	Commands  map[string]*ShellCommandRule `json:"commands"`  // 允许的可执行文件，键为命令名（按 PATH 解析）或绝对路径，为空时使用内置的只读命令列表
+	Sandbox   *SandboxConfig               `json:"sandbox"`   // Linux 沙箱，可在 profiles 中按调用方覆盖
+}
+
+// SandboxConfig RunSafeShellCommand 的 Linux 沙箱：在新的 user/mount/PID/network 命名空间中执行命令，无需 root
+type SandboxConfig struct {
+	Enabled      bool     `json:"enabled"`      // 是否启用
+	Network      bool     `json:"network"`      // 是否保留网络，默认 false（新的网络命名空间中没有可用网卡）
+	Binds        []string `json:"binds"`        // 额外只读挂载的目录，系统目录（/usr、/bin、/lib、/etc 等）与 shell.roots（未配置时为工作目录）始终挂载
+	CpuSeconds   uint64   `json:"cpuSeconds"`   // CPU 时间上限（秒），0 表示不限制
+	MemoryMB     uint64   `json:"memoryMB"`     // 虚拟内存（地址空间）上限（MB），0 表示不限制
+	MaxProcesses uint64   `json:"maxProcesses"` // 进程数上限，按宿主机上的同一用户计数，0 表示不限制
+	FileSizeMB   uint64   `json:"fileSizeMB"`   // 单个文件写入大小上限（MB），0 表示不限制
+}
+
+// ShellCommandRule 单个可执行文件的参数规则，flag 规则支持 glob（如 -exec*）
+type ShellCommandRule struct {
+	Paths           []string `json:"paths"`           // 允许的实际路径（解析符号链接后，支持 glob），为空时只允许按 PATH 解析到的路径
+	AllowedFlags    []string `json:"allowedFlags"`    // 允许的选项，为空表示不限制
+	ForbiddenFlags  []string `json:"forbiddenFlags"`  // 禁止的选项，优先于 allowedFlags
+	ArgPatterns     []string `json:"argPatterns"`     // 非选项参数需匹配其中之一的正则，为空表示不限制
+	DenyArgPatterns []string `json:"denyArgPatterns"` // 任一参数匹配即拒绝的正则
+	MaxArgs         int      `json:"maxArgs"`         // 最多参数个数，0 表示不限制
 }
 
 type DbConfig struct {
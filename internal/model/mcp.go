@@ -8,8 +8,16 @@ import (
 type McpReg struct {
 	Name        string
 	Description string
-	ToolOptions []mcp.ToolOption
-	Fn          server.ToolHandlerFunc
+	Requires    string   // 依赖的资源（consts.Resource*），对应配置未设置时不注册该工具
+	ErrorCodes  []string // 工具可能返回的错误码，注册时追加到描述中
+	// 工具注解，客户端据此决定是否需要用户确认
+	Title           string // 展示用标题
+	ReadOnlyHint    bool   // 不修改任何环境状态
+	DestructiveHint bool   // 可能执行破坏性修改（ReadOnlyHint 为 false 时有意义）
+	IdempotentHint  bool   // 相同参数重复调用不会产生额外影响
+	OpenWorldHint   bool   // 与外部开放系统交互
+	ToolOptions     []mcp.ToolOption
+	Fn              server.ToolHandlerFunc
 }
 
 type McpSendMessageInput struct {
@@ -17,3 +25,18 @@ type McpSendMessageInput struct {
 	AgentId string `json:"agentId"`
 	Account string `json:"account"`
 }
+
+// AuditRecord 工具调用审计记录，每次调用一行 JSON
+type AuditRecord struct {
+	Time       string         `json:"time"`       // RFC3339 毫秒时间
+	TraceId    string         `json:"traceId"`    // 链路追踪 ID，与日志、tracing 关联
+	SessionId  string         `json:"sessionId"`  // MCP 会话 ID
+	Caller     string         `json:"caller"`     // 调用方身份
+	Tool       string         `json:"tool"`       // 工具名
+	Arguments  map[string]any `json:"arguments"`  // 脱敏后的参数
+	DurationMs int64          `json:"durationMs"` // 耗时（毫秒）
+	Outcome    string         `json:"outcome"`    // success / tool-error / panic / denied
+	ResultSize int            `json:"resultSize"` // 结果 JSON 字节数
+	ResultHash string         `json:"resultHash"` // 结果 JSON 的 SHA-256
+	Error      string         `json:"error,omitempty"`
+}
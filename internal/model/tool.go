@@ -0,0 +1,87 @@
+package model
+
+// 工具结构化输出，字段即 outputSchema，文本内容为同一结构的 JSON 以兼容旧客户端
+
+type Md5EncodeOutput struct {
+	Md5 string `json:"md5" jsonschema:"description=MD5 digest in lower-case hex"`
+}
+
+type Base64EncodeOutput struct {
+	Base64 string `json:"base64" jsonschema:"description=Base64 encoded text"`
+}
+
+type Base64DecodeOutput struct {
+	Text string `json:"text" jsonschema:"description=Decoded text"`
+}
+
+type JwtParseOutput struct {
+	RawHeader    string         `json:"rawHeader"`
+	RawPayload   string         `json:"rawPayload"`
+	RawSignature string         `json:"rawSignature"`
+	Header       map[string]any `json:"header,omitempty" jsonschema:"description=Decoded header (omitted when it is not a JSON object)"`
+	Payload      map[string]any `json:"payload,omitempty" jsonschema:"description=Decoded payload (omitted when it is not a JSON object)"`
+}
+
+type JsonEncodeOutput struct {
+	Json string `json:"json" jsonschema:"description=Compacted JSON"`
+}
+
+type NowTimeOutput struct {
+	TimeZone        string `json:"timeZone" jsonschema:"description=IANA time zone of datetime"`
+	Datetime        string `json:"datetime" jsonschema:"description=Date time in the requested time zone (Y-m-d H:i:s)"`
+	UnixMillisecond int64  `json:"UnixMillisecond" jsonschema:"description=Unix timestamp in milliseconds"`
+}
+
+type TimestampToDateTimeOutput struct {
+	Datetime string `json:"datetime" jsonschema:"description=Local date time (Y-m-d H:i:s)"`
+}
+
+type CalendarDaysOutput struct {
+	Year      int           `json:"year"`
+	Month     int           `json:"month"`
+	MonthName string        `json:"monthName"`
+	TotalDays int           `json:"totalDays"`
+	Days      []CalendarDay `json:"days"`
+}
+
+type CalendarDay struct {
+	Date        string `json:"date"`
+	Day         int    `json:"day"`
+	Weekday     int    `json:"weekday" jsonschema:"description=0 = Sunday ... 6 = Saturday"`
+	WeekdayName string `json:"weekdayName"`
+	IsWeekend   bool   `json:"isWeekend"`
+}
+
+// SqlOutput SQL_Actuator 的结构化结果，rows 中的值保留数据库返回的类型
+type SqlOutput struct {
+	Columns  []SqlColumn      `json:"columns" jsonschema:"description=Result columns in query order"`
+	Rows     []map[string]any `json:"rows" jsonschema:"description=Result rows keyed by column name"`
+	RowCount int              `json:"rowCount"`
+}
+
+type SqlColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type" jsonschema:"description=Database type name reported by the driver (e.g. BIGINT, VARCHAR); empty if unknown"`
+	Nullable *bool  `json:"nullable,omitempty" jsonschema:"description=Whether the column allows NULL; omitted if the driver does not report it"`
+}
+
+type DatabaseInfoOutput struct {
+	DatabaseType string         `json:"databaseType"`
+	Host         string         `json:"host"`
+	Port         string         `json:"port"`
+	DatabaseName string         `json:"databaseName"`
+	Username     string         `json:"username"`
+	Prefix       string         `json:"prefix"`
+	CreatedAt    string         `json:"createdAt"`
+	UpdatedAt    string         `json:"updatedAt"`
+	Debug        bool           `json:"debug"`
+	Version      map[string]any `json:"version,omitempty"`
+	DatabaseSize map[string]any `json:"databaseSize,omitempty"`
+}
+
+// RedisOutput ExecRedisCommand 的结构化结果
+type RedisOutput struct {
+	Command string `json:"command"`
+	Type    string `json:"type" jsonschema:"enum=nil,enum=string,enum=integer,enum=array,enum=map,enum=other"`
+	Value   any    `json:"value" jsonschema:"description=Reply value; arrays and maps keep their nested structure"`
+}
@@ -0,0 +1,24 @@
+package redact
+
+import (
+	"ai-mcp/internal/consts"
+	"os"
+	"testing"
+)
+
+// TestMain 日志写入临时目录，避免测试在源码目录下生成 logs
+func TestMain(m *testing.M) {
+	os.Exit(runWithTempLogs(m))
+}
+
+func runWithTempLogs(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "ai-mcp-test-logs-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = consts.Logger.SetPath(dir); err != nil {
+		panic(err)
+	}
+	return m.Run()
+}
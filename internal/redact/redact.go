@@ -0,0 +1,230 @@
+package redact
+
+import (
+	"ai-mcp/internal/consts"
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/os/glog"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+const defaultMask = "***"
+
+// 参数名包含以下片段时视为敏感字段（默认策略）
+var defaultSensitiveKeys = []string{"token", "password", "passwd", "secret", "auth", "credential", "apikey", "api_key"}
+
+// 默认的内容匹配规则，对日志、审计、链路追踪中的任意文本生效
+var defaultPatterns = []pattern{
+	// JWT：三段 base64url，头部固定以 eyJ 开头
+	{name: "jwt", re: regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)},
+	// password=xxx / pwd: xxx / "password":"xxx" 等键值形式，仅遮蔽值
+	{name: "password", re: regexp.MustCompile(`(?i)((?:password|passwd|pwd|secret|token)["']?\s*[=:]\s*["']?)[^\s"'&,;)\]}]+`), replace: "${1}" + defaultMask},
+	// SQL 中的 IDENTIFIED BY 'xxx'
+	{name: "identified-by", re: regexp.MustCompile(`(?i)(identified\s+by\s+)'[^']*'`), replace: "${1}'" + defaultMask + "'"},
+	// 连接串中的 user:pass@
+	{name: "dsn-password", re: regexp.MustCompile(`([A-Za-z0-9_.-]+:)[^@\s/:]+@(tcp|unix|\[|[A-Za-z0-9.-]+[:/])`), replace: "${1}" + defaultMask + "@${2}"},
+	// 银行卡号：13-19 位数字，允许空格或短横线分隔，需通过 Luhn 校验以免误伤时间戳等数字
+	{name: "credit-card", re: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), match: luhn},
+}
+
+// Redis 中携带敏感值的命令：AUTH 遮蔽全部参数，其余命令仅遮蔽值、保留键名
+var (
+	redisMaskAll    = map[string]bool{"AUTH": true, "HELLO": true, "MIGRATE": true}
+	redisMaskValues = map[string]int{"SET": 1, "SETNX": 1, "SETEX": 2, "PSETEX": 2, "GETSET": 1, "APPEND": 1, "LPUSH": 1, "RPUSH": 1, "SADD": 1}
+	redisMaskPairs  = map[string]int{"MSET": 0, "MSETNX": 0, "HSET": 1, "HMSET": 1, "HSETNX": 1}
+	// CONFIG SET 中值为密码的配置项
+	redisSecretConfigs = map[string]bool{"REQUIREPASS": true, "MASTERAUTH": true}
+)
+
+type pattern struct {
+	name    string
+	re      *regexp.Regexp
+	replace string            // 为空时整体替换为遮蔽符
+	match   func(string) bool // 命中正则后的二次校验，为空表示直接替换
+}
+
+type rules struct {
+	mask          string
+	sensitiveKeys []string
+	tools         map[string][]string
+	patterns      []pattern
+}
+
+var (
+	loadOnce sync.Once
+	loaded   *rules
+)
+
+// getRules 首次使用时根据 redaction 配置编译规则
+func getRules() *rules {
+	loadOnce.Do(func() {
+		r := &rules{mask: defaultMask, tools: map[string][]string{}}
+		cfg := consts.Config.Redaction
+		if cfg != nil && cfg.Mask != "" {
+			r.mask = cfg.Mask
+		}
+		if cfg == nil || !cfg.DisableDefaults {
+			r.sensitiveKeys = append(r.sensitiveKeys, defaultSensitiveKeys...)
+			r.patterns = append(r.patterns, defaultPatterns...)
+		}
+		if cfg != nil {
+			for _, key := range cfg.SensitiveKeys {
+				r.sensitiveKeys = append(r.sensitiveKeys, strings.ToLower(key))
+			}
+			for tool, keys := range cfg.Tools {
+				r.tools[tool] = keys
+			}
+			for _, item := range cfg.Patterns {
+				re, err := regexp.Compile(item.Regex)
+				if err != nil {
+					consts.Logger.Warningf(consts.Ctx, "脱敏规则 %s 正则无效，已忽略: %s", item.Name, err.Error())
+					continue
+				}
+				r.patterns = append(r.patterns, pattern{name: item.Name, re: re, replace: item.Replace})
+			}
+		}
+		loaded = r
+	})
+	return loaded
+}
+
+// Args 复制工具参数并按规则脱敏：工具级参数名规则、敏感参数名、内容正则，超长值截断
+func Args(tool string, args map[string]any) map[string]any {
+	r := getRules()
+	out := make(map[string]any, len(args))
+	for key, value := range args {
+		if r.isSensitiveKey(tool, key) {
+			out[key] = r.mask
+			continue
+		}
+		if tool == "ExecRedisCommand" && key == "args" {
+			out[key] = r.redisArgs(gconv.String(args["command"]), value)
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			out[key] = truncate(String(v), 512)
+		case map[string]any:
+			out[key] = Args(tool, v)
+		default:
+			out[key] = value
+		}
+	}
+	return out
+}
+
+// String 按内容正则脱敏任意文本
+func String(s string) string {
+	r := getRules()
+	for _, p := range r.patterns {
+		switch {
+		case p.match != nil:
+			s = p.re.ReplaceAllStringFunc(s, func(m string) string {
+				if p.match(m) {
+					return r.mask
+				}
+				return m
+			})
+		case p.replace != "":
+			s = p.re.ReplaceAllString(s, p.replace)
+		default:
+			s = p.re.ReplaceAllString(s, r.mask)
+		}
+	}
+	return s
+}
+
+// LogHandler glog 处理器，对全部 logger（含 gdb 的 SQL 调试日志）输出内容脱敏
+func LogHandler(ctx context.Context, in *glog.HandlerInput) {
+	for i, value := range in.Values {
+		if str, ok := value.(string); ok {
+			in.Values[i] = String(str)
+		}
+	}
+	in.Content = String(in.Content)
+	in.Next(ctx)
+}
+
+func (r *rules) isSensitiveKey(tool, key string) bool {
+	for _, name := range r.tools[tool] {
+		if name == "*" || strings.EqualFold(name, key) {
+			return true
+		}
+	}
+	lower := strings.ToLower(key)
+	for _, fragment := range r.sensitiveKeys {
+		if strings.Contains(lower, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// redisArgs 按 Redis 命令语义遮蔽参数中的值，args 可以是 JSON 数组字符串或数组
+func (r *rules) redisArgs(command string, value any) any {
+	var list []string
+	if str, ok := value.(string); ok {
+		if err := gjson.Unmarshal([]byte(str), &list); err != nil {
+			return String(str)
+		}
+	} else {
+		list = gconv.Strings(value)
+	}
+	command = strings.ToUpper(command)
+	subcommand := ""
+	if len(list) > 0 {
+		subcommand = strings.ToUpper(list[0])
+	}
+	for i := range list {
+		switch {
+		case redisMaskAll[command]:
+			list[i] = r.mask
+		// CONFIG SET name value [name value ...]
+		case command == "CONFIG" && subcommand == "SET" && i >= 2 && i%2 == 0 && redisSecretConfigs[strings.ToUpper(list[i-1])]:
+			list[i] = r.mask
+		// ACL SETUSER 中以 > 或 # 开头的规则为明文密码或密码哈希
+		case command == "ACL" && subcommand == "SETUSER" && i >= 2 && (strings.HasPrefix(list[i], ">") || strings.HasPrefix(list[i], "#")):
+			list[i] = r.mask
+		case redisMaskValues[command] > 0 && i >= redisMaskValues[command]:
+			list[i] = r.mask
+		default:
+			if offset, ok := redisMaskPairs[command]; ok && i > offset && (i-offset)%2 == 1 {
+				list[i] = r.mask
+			} else {
+				list[i] = String(list[i])
+			}
+		}
+	}
+	return list
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "...[truncated " + gconv.String(len(s)-max) + " bytes]"
+}
+
+// luhn 校验银行卡号，忽略空格与短横线
+func luhn(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
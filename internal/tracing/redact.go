@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"ai-mcp/internal/redact"
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// redactExporter 导出前对 span 属性与事件中的字符串值脱敏，覆盖 gdb 的 SQL 语句与 Redis 命令参数
+type redactExporter struct {
+	sdktrace.SpanExporter
+}
+
+type redactedSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+func (e redactExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	wrapped := make([]sdktrace.ReadOnlySpan, len(spans))
+	for i, span := range spans {
+		wrapped[i] = redactedSpan{span}
+	}
+	return e.SpanExporter.ExportSpans(ctx, wrapped)
+}
+
+func (s redactedSpan) Attributes() []attribute.KeyValue {
+	return redactAttributes(s.ReadOnlySpan.Attributes())
+}
+
+func (s redactedSpan) Events() []sdktrace.Event {
+	events := s.ReadOnlySpan.Events()
+	out := make([]sdktrace.Event, len(events))
+	for i, event := range events {
+		event.Attributes = redactAttributes(event.Attributes)
+		out[i] = event
+	}
+	return out
+}
+
+func redactAttributes(attrs []attribute.KeyValue) []attribute.KeyValue {
+	out := make([]attribute.KeyValue, len(attrs))
+	for i, kv := range attrs {
+		if kv.Value.Type() == attribute.STRING {
+			kv.Value = attribute.StringValue(redact.String(kv.Value.AsString()))
+		}
+		out[i] = kv
+	}
+	return out
+}
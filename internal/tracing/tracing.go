@@ -0,0 +1,108 @@
+package tracing
+
+import (
+	"ai-mcp/internal/consts"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentName = "ai-mcp"
+
+// Init 按 tracing 配置初始化全局 TracerProvider，gdb 与 Redis 的 span 由 GoFrame 基于全局 Provider 自动创建
+// 返回的 shutdown 用于退出前刷新并关闭导出器，未启用时为空操作
+func Init(ctx context.Context) (shutdown func(ctx context.Context) error, err error) {
+	shutdown = func(ctx context.Context) error { return nil }
+	cfg := consts.Config.Tracing
+	if cfg == nil || !cfg.Enabled {
+		return
+	}
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case "otlp":
+		opts := []otlptracehttp.Option{}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	case "stdout", "":
+		// stdio 模式下 stdout 承载 JSON-RPC 消息，输出到 stderr
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	case "file":
+		var w io.Writer
+		if w, err = openFile(cfg.File); err == nil {
+			exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+		}
+	default:
+		err = fmt.Errorf("不支持的 tracing.exporter: %s（可选 otlp / stdout / file）", cfg.Exporter)
+	}
+	if err != nil {
+		return
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "ai-mcp"
+	}
+	sampleRatio := cfg.SampleRatio
+	if sampleRatio <= 0 {
+		sampleRatio = 1
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(redactExporter{exporter}),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(consts.Version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	consts.Logger.Infof(ctx, "链路追踪已启用，导出方式: %s", cfg.Exporter)
+	shutdown = provider.Shutdown
+	return
+}
+
+// Start 创建一个 span，未启用追踪时返回空操作 span
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End 记录结果并结束 span，err 不为空时标记为错误
+func End(span trace.Span, outcome string, err error) {
+	span.SetAttributes(attribute.String("mcp.tool.outcome", outcome))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else if outcome != "success" {
+		span.SetStatus(codes.Error, outcome)
+	}
+	span.End()
+}
+
+func openFile(path string) (*os.File, error) {
+	if path == "" {
+		path = "./logs/traces.jsonl"
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+}
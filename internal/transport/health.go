@@ -0,0 +1,109 @@
+package transport
+
+import (
+	"ai-mcp/internal/auth"
+	"ai-mcp/internal/consts"
+	"ai-mcp/internal/metrics"
+	"context"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// readyTimeout 就绪检查中单个依赖的 ping 超时
+const readyTimeout = 2 * time.Second
+
+// registerAdmin 注册 /healthz、/readyz、/version、/metrics，前三者不经过认证。
+// 与 MCP 服务共用监听（shared 为 true）时 /metrics 需要认证，避免指标中的调用方身份暴露给未认证的请求
+func (s *sTransport) registerAdmin(mux *http.ServeMux, shared bool) {
+	mux.HandleFunc("/healthz", s.healthz)
+	mux.HandleFunc("/readyz", s.readyz)
+	mux.HandleFunc("/version", s.version)
+	if shared {
+		mux.Handle("/metrics", auth.Auth.Middleware(metrics.Handler()))
+	} else {
+		mux.Handle("/metrics", metrics.Handler())
+	}
+}
+
+// healthz 存活检查，进程可响应即返回 200
+func (s *sTransport) healthz(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, g.Map{"status": "ok"})
+}
+
+// readyz 就绪检查，逐个 ping 已配置的数据库与 Redis，任一失败或服务关闭中返回 503
+func (s *sTransport) readyz(w http.ResponseWriter, r *http.Request) {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		ready  = !s.shuttingDown.Load()
+		checks = g.Map{}
+	)
+	check := func(name string, ping func(ctx context.Context) error) {
+		defer wg.Done()
+		ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+		defer cancel()
+		start := time.Now()
+		err := ping(ctx)
+		item := g.Map{"status": "ok", "durationMs": time.Since(start).Milliseconds()}
+		if err != nil {
+			item["status"] = "error"
+			item["error"] = err.Error()
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		checks[name] = item
+		if err != nil {
+			ready = false
+		}
+	}
+
+	for _, group := range consts.DbGroups() {
+		wg.Add(1)
+		go check("database."+group, func(ctx context.Context) error {
+			db, err := g.DB(group).Master()
+			if err != nil {
+				return err
+			}
+			return db.PingContext(ctx)
+		})
+	}
+	for _, name := range consts.RedisGroups() {
+		wg.Add(1)
+		go check("redis."+name, func(ctx context.Context) error {
+			_, err := g.Redis(name).Do(ctx, "PING")
+			return err
+		})
+	}
+	wg.Wait()
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not ready", http.StatusServiceUnavailable
+	}
+	writeJson(w, code, g.Map{
+		"status":       status,
+		"shuttingDown": s.shuttingDown.Load(),
+		"checks":       checks,
+	})
+}
+
+// version 构建版本信息
+func (s *sTransport) version(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, g.Map{
+		"version":   consts.Version,
+		"commit":    consts.Commit,
+		"buildTime": consts.BuildTime,
+		"goVersion": runtime.Version(),
+	})
+}
+
+func writeJson(w http.ResponseWriter, code int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write([]byte(gjson.MustEncodeString(data)))
+}
@@ -0,0 +1,52 @@
+package transport
+
+import (
+	"ai-mcp/internal/consts"
+	"ai-mcp/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	// /metrics 采集数据库连接池指标时需要驱动，与 main.go 一致
+	_ "github.com/gogf/gf/contrib/drivers/mysql/v2"
+)
+
+func TestRegisterAdminMetricsAuth(t *testing.T) {
+	authCfg := consts.Config.McpServer.Auth
+	consts.Config.McpServer.Auth = &model.AuthConfig{
+		Enabled: true,
+		ApiKeys: []model.AuthApiKey{{Name: "ops", Key: "test-key"}},
+	}
+	t.Cleanup(func() {
+		consts.Config.McpServer.Auth = authCfg
+	})
+
+	tests := []struct {
+		name   string
+		shared bool
+		path   string
+		key    string
+		want   int
+	}{
+		{name: "共用监听 healthz 无需认证", shared: true, path: "/healthz", want: http.StatusOK},
+		{name: "共用监听 version 无需认证", shared: true, path: "/version", want: http.StatusOK},
+		{name: "共用监听 metrics 未认证", shared: true, path: "/metrics", want: http.StatusUnauthorized},
+		{name: "共用监听 metrics 已认证", shared: true, path: "/metrics", key: "test-key", want: http.StatusOK},
+		{name: "独立监听 metrics 无需认证", shared: false, path: "/metrics", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			(&sTransport{}).registerAdmin(mux, tt.shared)
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package transport
+
+import (
+	"ai-mcp/internal/auth"
+	"ai-mcp/internal/consts"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/os/gcache"
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const sessionIdPrefix = "mcp-session-"
+
+// pendingCaller Generate 签发的占位 ID 绑定的调用方，不会与任何真实调用方相同
+const pendingCaller = "\x00pending"
+
+// 签发时间允许的时钟偏差
+const sessionClockSkew = time.Minute
+
+var (
+	errInvalidSession = errors.New("invalid session id")
+	errSessionExpired = errors.New("session expired")
+)
+
+// sessionIdManager Streamable HTTP 会话 ID 管理
+// 会话 ID 由 uuid、签发时间、调用方标识与 HMAC 签名组成，签发后 ttl 内由同一调用方使用时有效，
+// 客户端断线重连后无需重新 initialize；配置密钥后服务重启仍接受未过期的 ID。
+// 被客户端主动终止（DELETE）的会话与用于断线续传的事件只保存在当前进程内
+type sessionIdManager struct {
+	secret     []byte
+	ttl        time.Duration
+	now        func() time.Time
+	terminated *gcache.Cache
+	events     *eventStore
+}
+
+func newSessionIdManager(secret string, ttl time.Duration) *sessionIdManager {
+	key := []byte(secret)
+	if len(key) == 0 {
+		// 未配置密钥时使用进程级随机密钥，会话仅在本次进程生命周期内有效
+		key = make([]byte, 32)
+		_, _ = rand.Read(key)
+	}
+	return &sessionIdManager{
+		secret:     key,
+		ttl:        ttl,
+		now:        time.Now,
+		terminated: gcache.New(),
+		events:     newEventStore(ttl),
+	}
+}
+
+// Generate 由 mcp-go 在处理 initialize 时调用，此时无法获知调用方，先签发占位 ID，
+// 响应写出前由 Handler 换成绑定调用方的 ID
+func (m *sessionIdManager) Generate() string {
+	return m.issue(pendingCaller)
+}
+
+func (m *sessionIdManager) Validate(sessionID string) (isTerminated bool, err error) {
+	if _, err = m.parse(sessionID); err != nil {
+		return
+	}
+	isTerminated = m.terminated.MustContains(consts.Ctx, sessionID)
+	return
+}
+
+func (m *sessionIdManager) Terminate(sessionID string) (isNotAllowed bool, err error) {
+	if _, err = m.Validate(sessionID); err != nil {
+		return
+	}
+	m.events.remove(sessionID)
+	err = m.terminated.Set(consts.Ctx, sessionID, true, m.ttl)
+	return
+}
+
+// Handler 在 mcp-go 之前处理会话 ID：initialize 响应中的占位 ID 换成绑定当前调用方的 ID；
+// 其余请求携带的 ID 无效、过期、已终止或属于其他调用方时返回 404，客户端需重新 initialize。校验通过的请求支持断线续传
+func (m *sessionIdManager) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller := auth.GetIdentity(r.Context()).String()
+		sessionID := r.Header.Get(server.HeaderKeySessionID)
+		if sessionID == "" {
+			if r.Method == http.MethodPost {
+				w = &sessionIdWriter{ResponseWriter: w, issue: func() string {
+					return m.issue(caller)
+				}}
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !m.owns(sessionID, caller) || m.terminated.MustContains(consts.Ctx, sessionID) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		m.events.serve(w, r, sessionID, next)
+	})
+}
+
+// issue 为调用方签发新的会话 ID
+func (m *sessionIdManager) issue(caller string) string {
+	payload := uuid.New().String() + "." + strconv.FormatInt(m.now().Unix(), 10) + "." + m.callerTag(caller)
+	return sessionIdPrefix + payload + "." + m.sign(payload)
+}
+
+// parse 校验签名与有效期，返回签发时绑定的调用方标识
+func (m *sessionIdManager) parse(sessionID string) (callerTag string, err error) {
+	payload, ok := strings.CutPrefix(sessionID, sessionIdPrefix)
+	i := strings.LastIndexByte(payload, '.')
+	if !ok || i < 0 || !hmac.Equal([]byte(payload[i+1:]), []byte(m.sign(payload[:i]))) {
+		err = errInvalidSession
+		return
+	}
+	parts := strings.Split(payload[:i], ".")
+	if len(parts) != 3 {
+		err = errInvalidSession
+		return
+	}
+	issuedAt, parseErr := strconv.ParseInt(parts[1], 10, 64)
+	if parseErr != nil {
+		err = errInvalidSession
+		return
+	}
+	if age := m.now().Sub(time.Unix(issuedAt, 0)); age > m.ttl || age < -sessionClockSkew {
+		err = errSessionExpired
+		return
+	}
+	return parts[2], nil
+}
+
+// owns 会话 ID 是否有效且签发给该调用方
+func (m *sessionIdManager) owns(sessionID, caller string) bool {
+	callerTag, err := m.parse(sessionID)
+	return err == nil && hmac.Equal([]byte(callerTag), []byte(m.callerTag(caller)))
+}
+
+// callerTag 调用方标识的 HMAC，会话 ID 中不直接暴露调用方名称
+func (m *sessionIdManager) callerTag(caller string) string {
+	return m.sign("caller\x00" + caller)[:16]
+}
+
+func (m *sessionIdManager) sign(payload string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// sessionIdWriter 在 initialize 响应写出前把 mcp-go 生成的占位会话 ID 换成绑定调用方的 ID
+type sessionIdWriter struct {
+	http.ResponseWriter
+	issue     func() string
+	rewritten bool
+}
+
+func (w *sessionIdWriter) rewrite() {
+	if w.rewritten {
+		return
+	}
+	w.rewritten = true
+	if w.Header().Get(server.HeaderKeySessionID) != "" {
+		w.Header().Set(server.HeaderKeySessionID, w.issue())
+	}
+}
+
+func (w *sessionIdWriter) WriteHeader(code int) {
+	w.rewrite()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *sessionIdWriter) Write(p []byte) (int, error) {
+	w.rewrite()
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *sessionIdWriter) Flush() {
+	w.rewrite()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,344 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-mcp/internal/consts"
+
+	"github.com/gogf/gf/v2/os/gcache"
+)
+
+const (
+	maxStreamEvents   = 1000             // 每个流最多保留的事件数，超出时丢弃最早的事件
+	maxSessionStreams = 32               // 每个会话最多保留的流，超出时丢弃最早的流
+	streamRetention   = 10 * time.Minute // 流结束后事件的保留时长
+)
+
+// eventStore 断线续传的事件存储，按会话保存每个 SSE 流已发送的事件。
+// 事件 ID 为「流 ID-序号」，客户端以 GET 携带 Last-Event-ID 重连时重放该流中之后的事件
+type eventStore struct {
+	ttl      time.Duration
+	sessions *gcache.Cache
+}
+
+// sessionStreams 单个会话的全部流
+type sessionStreams struct {
+	mu      sync.Mutex
+	streams map[string]*eventStream
+	order   []string
+}
+
+// eventStream 单个 SSE 流：POST 请求的响应流或 GET 监听流
+type eventStream struct {
+	id       string
+	listen   bool // 是否为 GET 监听流
+	mu       sync.Mutex
+	events   []sseEvent
+	seq      int
+	done     bool
+	finished time.Time
+	changed  chan struct{} // 有新事件或流结束时关闭并替换
+}
+
+type sseEvent struct {
+	seq  int
+	data []byte
+}
+
+func newEventStore(ttl time.Duration) *eventStore {
+	return &eventStore{ttl: ttl, sessions: gcache.New()}
+}
+
+// serve 处理已校验会话 ID 的请求：GET 监听流与接受 SSE 的 POST 请求改为带事件 ID 的 SSE 流并记录事件，
+// GET 携带 Last-Event-ID 时先重放对应流中之后的事件
+func (s *eventStore) serve(w http.ResponseWriter, r *http.Request, sessionID string, next http.Handler) {
+	switch r.Method {
+	case http.MethodGet:
+		writer := s.newStreamWriter(w, sessionID, true)
+		defer writer.stream.finish()
+		if streamId, seq, ok := parseEventId(r.Header.Get("Last-Event-ID")); ok {
+			if stream := s.lookup(sessionID, streamId); stream != nil {
+				writer.start()
+				// POST 响应流跟随到请求处理结束；监听流重放后继续作为新的监听流
+				if !stream.listen {
+					stream.replay(writer.w, r.Context(), seq, true)
+					return
+				}
+				stream.replay(writer.w, r.Context(), seq, false)
+			}
+		}
+		next.ServeHTTP(writer, r)
+	case http.MethodPost:
+		// 响应流开始后无法再返回错误状态码，mcp-go 会拒绝的请求交给它原样处理
+		if !acceptsEventStream(r) || !isJsonContent(r) || !isJsonRpcRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// 客户端断开后继续处理请求并记录响应，重连后可以取回；调用可通过 notifications/cancelled 或超时结束
+		writer := s.newStreamWriter(w, sessionID, false)
+		defer writer.stream.finish()
+		writer.start()
+		writer.prime()
+		next.ServeHTTP(writer, r.WithContext(context.WithoutCancel(r.Context())))
+	default:
+		next.ServeHTTP(w, r)
+	}
+}
+
+// remove 删除会话的全部事件，会话终止时调用
+func (s *eventStore) remove(sessionID string) {
+	_, _ = s.sessions.Remove(consts.Ctx, sessionID)
+}
+
+func (s *eventStore) session(sessionID string) *sessionStreams {
+	value, _ := s.sessions.GetOrSetFuncLock(consts.Ctx, sessionID, func(ctx context.Context) (any, error) {
+		return &sessionStreams{streams: map[string]*eventStream{}}, nil
+	}, s.ttl)
+	return value.Val().(*sessionStreams)
+}
+
+func (s *eventStore) lookup(sessionID, streamId string) *eventStream {
+	value, _ := s.sessions.Get(consts.Ctx, sessionID)
+	if value.IsNil() {
+		return nil
+	}
+	session := value.Val().(*sessionStreams)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.streams[streamId]
+}
+
+func (s *eventStore) newStreamWriter(w http.ResponseWriter, sessionID string, listen bool) *streamWriter {
+	return &streamWriter{w: w, header: http.Header{}, stream: s.session(sessionID).newStream(listen)}
+}
+
+// newStream 创建流，同时清理已结束超过保留时长的流
+func (ss *sessionStreams) newStream(listen bool) *eventStream {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	order := ss.order[:0]
+	for _, id := range ss.order {
+		if ss.streams[id].expired() {
+			delete(ss.streams, id)
+			continue
+		}
+		order = append(order, id)
+	}
+	for len(order) >= maxSessionStreams {
+		delete(ss.streams, order[0])
+		order = order[1:]
+	}
+	ss.order = order
+
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	stream := &eventStream{id: hex.EncodeToString(buf), listen: listen, changed: make(chan struct{})}
+	ss.streams[stream.id] = stream
+	ss.order = append(ss.order, stream.id)
+	return stream
+}
+
+func (es *eventStream) expired() bool {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	return es.done && time.Since(es.finished) > streamRetention
+}
+
+func (es *eventStream) eventId(seq int) string {
+	return es.id + "-" + strconv.Itoa(seq)
+}
+
+func (es *eventStream) append(data []byte) (seq int) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.seq++
+	es.events = append(es.events, sseEvent{seq: es.seq, data: data})
+	if len(es.events) > maxStreamEvents {
+		es.events = es.events[len(es.events)-maxStreamEvents:]
+	}
+	close(es.changed)
+	es.changed = make(chan struct{})
+	return es.seq
+}
+
+func (es *eventStream) finish() {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if es.done {
+		return
+	}
+	es.done, es.finished = true, time.Now()
+	close(es.changed)
+}
+
+// after 返回序号大于 seq 的事件
+func (es *eventStream) after(seq int) (events []sseEvent, done bool, changed <-chan struct{}) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	for _, event := range es.events {
+		if event.seq > seq {
+			events = append(events, event)
+		}
+	}
+	return events, es.done, es.changed
+}
+
+// replay 写出序号大于 seq 的事件，follow 为 true 时继续写出新事件直到流结束或客户端断开
+func (es *eventStream) replay(w http.ResponseWriter, ctx context.Context, seq int, follow bool) {
+	for {
+		events, done, changed := es.after(seq)
+		for _, event := range events {
+			_ = writeEvent(w, es.eventId(event.seq), event.data)
+			seq = event.seq
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		if !follow || done {
+			return
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// streamWriter 把 mcp-go 写出的 SSE 事件或 JSON 响应改写为带事件 ID 的 SSE 事件并记录到流中，
+// 客户端断开后写入失败也继续记录，以便重连后重放。mcp-go 返回非 200 的错误响应时原样写出
+type streamWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	stream      *eventStream
+	started     bool
+	passthrough bool
+}
+
+func (s *streamWriter) Header() http.Header {
+	return s.header
+}
+
+func (s *streamWriter) WriteHeader(code int) {
+	if s.started || s.passthrough {
+		return
+	}
+	if code != http.StatusOK {
+		s.passthrough = true
+		for key, values := range s.header {
+			s.w.Header()[key] = values
+		}
+		s.w.WriteHeader(code)
+		return
+	}
+	s.start()
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if s.passthrough {
+		return s.w.Write(p)
+	}
+	s.start()
+	for _, data := range eventPayloads(p, s.header.Get("Content-Type")) {
+		_ = writeEvent(s.w, s.stream.eventId(s.stream.append(data)), data)
+	}
+	s.Flush()
+	return len(p), nil
+}
+
+func (s *streamWriter) Flush() {
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// start 写出 SSE 响应头
+func (s *streamWriter) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	header := s.w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	s.w.WriteHeader(http.StatusOK)
+}
+
+// prime 写出只有事件 ID 的事件：客户端记录该 ID 但不分发消息，响应返回前断开也可以凭它重连
+func (s *streamWriter) prime() {
+	_, _ = fmt.Fprintf(s.w, "id: %s\n\n", s.stream.eventId(0))
+	s.Flush()
+}
+
+func writeEvent(w io.Writer, id string, data []byte) error {
+	_, err := fmt.Fprintf(w, "id: %s\nevent: message\ndata: %s\n\n", id, data)
+	return err
+}
+
+// eventPayloads 取出 mcp-go 一次写入中的消息：SSE 时为各事件的 data，JSON 响应时为整个响应体
+func eventPayloads(p []byte, contentType string) (payloads [][]byte) {
+	if !strings.HasPrefix(contentType, "text/event-stream") {
+		if data := bytes.TrimSpace(p); len(data) > 0 {
+			payloads = append(payloads, append([]byte(nil), data...))
+		}
+		return
+	}
+	for _, frame := range bytes.Split(p, []byte("\n\n")) {
+		var lines [][]byte
+		for _, line := range bytes.Split(frame, []byte("\n")) {
+			if data, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+				lines = append(lines, bytes.TrimPrefix(data, []byte(" ")))
+			}
+		}
+		if len(lines) > 0 {
+			payloads = append(payloads, bytes.Join(lines, []byte("\n")))
+		}
+	}
+	return
+}
+
+func parseEventId(id string) (streamId string, seq int, ok bool) {
+	streamId, seqText, found := strings.Cut(id, "-")
+	if !found || streamId == "" {
+		return
+	}
+	seq, err := strconv.Atoi(seqText)
+	return streamId, seq, err == nil && seq >= 0
+}
+
+func isJsonContent(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// isJsonRpcRequest 请求体是否为单个 JSON-RPC 请求（带 method 与 id），通知与客户端的响应不需要响应流。
+// 读取后恢复请求体，交给 mcp-go 继续处理
+func isJsonRpcRequest(r *http.Request) bool {
+	body, err := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	var message struct {
+		Method string          `json:"method"`
+		Id     json.RawMessage `json:"id"`
+	}
+	return json.Unmarshal(body, &message) == nil && message.Method != "" && len(message.Id) > 0 && string(message.Id) != "null"
+}
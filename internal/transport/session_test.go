@@ -0,0 +1,200 @@
+package transport
+
+import (
+	"ai-mcp/internal/auth"
+	"ai-mcp/internal/model"
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const testCallerHeader = "X-Test-Caller"
+
+// newTestSessionServer 启动有状态的 Streamable HTTP 服务，调用方身份取自 X-Test-Caller 请求头。
+// wait 工具阻塞到 release 关闭后返回 done
+func newTestSessionServer(t *testing.T, m *sessionIdManager, release <-chan struct{}) *httptest.Server {
+	t.Helper()
+	mcpServer := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(false))
+	mcpServer.AddTool(mcp.NewTool("wait"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		select {
+		case <-release:
+			return mcp.NewToolResultText("done"), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+	handler := m.Handler(server.NewStreamableHTTPServer(mcpServer, server.WithSessionIdManager(m)))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity := &model.Identity{Name: r.Header.Get(testCallerHeader), Method: "api-key"}
+		handler.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), identity)))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newTestRequest(t *testing.T, ctx context.Context, method, url, caller, sessionID, body string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	req.Header.Set(testCallerHeader, caller)
+	if sessionID != "" {
+		req.Header.Set(server.HeaderKeySessionID, sessionID)
+	}
+	return req
+}
+
+// initializeSession 以 caller 身份 initialize，返回服务端签发的会话 ID
+func initializeSession(t *testing.T, url, caller string) string {
+	t.Helper()
+	body := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`
+	resp, err := http.DefaultClient.Do(newTestRequest(t, context.Background(), http.MethodPost, url, caller, "", body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	sessionID := resp.Header.Get(server.HeaderKeySessionID)
+	if resp.StatusCode != http.StatusOK || sessionID == "" {
+		t.Fatalf("initialize = %d，会话 ID %q", resp.StatusCode, sessionID)
+	}
+	return sessionID
+}
+
+// readEvent 读取一个 SSE 事件
+func readEvent(t *testing.T, reader *bufio.Reader) (id, data string) {
+	t.Helper()
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("读取 SSE 事件: %v", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			if id != "" || data != "" {
+				return
+			}
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestSessionIdBinding(t *testing.T) {
+	m := newSessionIdManager("test-secret", time.Hour)
+	ts := newTestSessionServer(t, m, nil)
+	sessionID := initializeSession(t, ts.URL, "alice")
+	if !m.owns(sessionID, "api-key:alice") {
+		t.Fatalf("initialize 签发的会话 ID 未绑定调用方：%s", sessionID)
+	}
+
+	expired := newSessionIdManager("test-secret", time.Hour)
+	expired.now = func() time.Time { return time.Now().Add(-2 * time.Hour) }
+	otherSecret := newSessionIdManager("other-secret", time.Hour)
+
+	tests := []struct {
+		name      string
+		caller    string
+		sessionID string
+		want      int
+	}{
+		{name: "签发给自己的会话", caller: "alice", sessionID: sessionID, want: http.StatusOK},
+		{name: "其他调用方使用", caller: "bob", sessionID: sessionID, want: http.StatusNotFound},
+		{name: "已过期", caller: "alice", sessionID: expired.issue("api-key:alice"), want: http.StatusNotFound},
+		{name: "其他密钥签发", caller: "alice", sessionID: otherSecret.issue("api-key:alice"), want: http.StatusNotFound},
+		{name: "篡改签名", caller: "alice", sessionID: sessionID[:len(sessionID)-1] + "0", want: http.StatusNotFound},
+		{name: "mcp-go 的占位 ID", caller: "alice", sessionID: m.Generate(), want: http.StatusNotFound},
+		{name: "格式错误", caller: "alice", sessionID: "mcp-session-x", want: http.StatusNotFound},
+	}
+	body := `{"jsonrpc":"2.0","id":2,"method":"ping"}`
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.DefaultClient.Do(newTestRequest(t, context.Background(), http.MethodPost, ts.URL, tt.caller, tt.sessionID, body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("ping = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+
+	// 终止后不可再使用
+	resp, err := http.DefaultClient.Do(newTestRequest(t, context.Background(), http.MethodDelete, ts.URL, "alice", sessionID, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	resp, err = http.DefaultClient.Do(newTestRequest(t, context.Background(), http.MethodPost, ts.URL, "alice", sessionID, body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("终止后 ping = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestSessionResume(t *testing.T) {
+	m := newSessionIdManager("test-secret", time.Hour)
+	release := make(chan struct{})
+	ts := newTestSessionServer(t, m, release)
+	sessionID := initializeSession(t, ts.URL, "alice")
+
+	// 调用进行中断开连接：只收到用于重连的事件 ID
+	ctx, cancel := context.WithCancel(context.Background())
+	body := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"wait","arguments":{}}}`
+	resp, err := http.DefaultClient.Do(newTestRequest(t, ctx, http.MethodPost, ts.URL, "alice", sessionID, body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/event-stream") {
+		t.Fatalf("tools/call 响应类型 %q，期望 SSE", contentType)
+	}
+	lastEventId, data := readEvent(t, bufio.NewReader(resp.Body))
+	if lastEventId == "" || data != "" {
+		t.Fatalf("首个事件 id %q data %q，期望只有事件 ID", lastEventId, data)
+	}
+	cancel()
+	_ = resp.Body.Close()
+	close(release)
+
+	// 其他调用方不能凭事件 ID 取回
+	get := newTestRequest(t, context.Background(), http.MethodGet, ts.URL, "bob", sessionID, "")
+	get.Header.Set("Last-Event-ID", lastEventId)
+	if resp, err = http.DefaultClient.Do(get); err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("其他调用方重连 = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+
+	// 重连后取回断开期间完成的调用结果
+	get = newTestRequest(t, context.Background(), http.MethodGet, ts.URL, "alice", sessionID, "")
+	get.Header.Set("Last-Event-ID", lastEventId)
+	if resp, err = http.DefaultClient.Do(get); err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("重连 = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	id, data := readEvent(t, bufio.NewReader(resp.Body))
+	if id == lastEventId || !strings.Contains(data, `"id":2`) || !strings.Contains(data, "done") {
+		t.Errorf("重连后的事件 id %q data %s，期望 tools/call 的结果", id, data)
+	}
+}
@@ -0,0 +1,65 @@
+package transport
+
+import (
+	"ai-mcp/internal/consts"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// tlsEnabled 是否启用 HTTPS
+func tlsEnabled() bool {
+	return consts.Config.McpServer.Tls != nil && consts.Config.McpServer.Tls.Enabled
+}
+
+// scheme 监听地址对应的 URL 协议，用于日志输出
+func scheme() string {
+	if tlsEnabled() {
+		return "https"
+	}
+	return "http"
+}
+
+// buildTlsConfig 根据 mcp-server.tls 构建 TLS 配置，配置 clientCaFile 时启用双向 TLS
+func buildTlsConfig() (tlsConfig *tls.Config, err error) {
+	cfg := consts.Config.McpServer.Tls
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		err = errors.New("启用 TLS 时必须配置 certFile 与 keyFile")
+		return
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		err = fmt.Errorf("加载 TLS 证书失败: %w", err)
+		return
+	}
+	tlsConfig = &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if cfg.ClientCaFile == "" {
+		return
+	}
+
+	caPem, err := os.ReadFile(cfg.ClientCaFile)
+	if err != nil {
+		err = fmt.Errorf("读取客户端 CA 失败: %w", err)
+		return
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPem) {
+		err = errors.New("客户端 CA 文件中没有有效证书")
+		return
+	}
+	tlsConfig.ClientCAs = pool
+	switch cfg.ClientAuth {
+	case "", "require":
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	case "optional":
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		err = fmt.Errorf("不支持的 clientAuth: %s（可选 require / optional）", cfg.ClientAuth)
+	}
+	return
+}
@@ -0,0 +1,270 @@
+package transport
+
+import (
+	"ai-mcp/internal/auth"
+	"ai-mcp/internal/consts"
+	"ai-mcp/internal/model"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testPki 测试用的自签名 CA、服务端证书与客户端证书
+type testPki struct {
+	dir        string
+	caFile     string
+	certFile   string
+	keyFile    string
+	caPool     *x509.CertPool
+	clientCert tls.Certificate
+	otherCert  tls.Certificate // 由另一个 CA 签发，服务端不信任
+}
+
+func newTestPki(t *testing.T) *testPki {
+	t.Helper()
+	p := &testPki{dir: t.TempDir()}
+	ca, caKey := newCertificate(t, nil, nil, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "ai-mcp test ca"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+	p.caPool = x509.NewCertPool()
+	p.caPool.AddCert(ca)
+	p.caFile = p.writePem(t, "ca.pem", "CERTIFICATE", ca.Raw)
+
+	server, serverKey := newCertificate(t, ca, caKey, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	p.certFile = p.writePem(t, "server.pem", "CERTIFICATE", server.Raw)
+	keyDer, err := x509.MarshalECPrivateKey(serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.keyFile = p.writePem(t, "server-key.pem", "EC PRIVATE KEY", keyDer)
+
+	client, clientKey := newCertificate(t, ca, caKey, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "sre-bot", Organization: []string{"ops"}},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	p.clientCert = tls.Certificate{Certificate: [][]byte{client.Raw}, PrivateKey: clientKey}
+
+	otherCa, otherCaKey := newCertificate(t, nil, nil, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "untrusted ca"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+	other, otherKey := newCertificate(t, otherCa, otherCaKey, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "intruder"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	p.otherCert = tls.Certificate{Certificate: [][]byte{other.Raw}, PrivateKey: otherKey}
+	return p
+}
+
+// newCertificate 生成证书，parent 为 nil 时自签名
+func newCertificate(t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, template *x509.Certificate) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func (p *testPki) writePem(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+	file := filepath.Join(p.dir, name)
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// setTlsConfig 临时替换 mcp-server.tls 与 mcp-server.auth，测试结束后恢复
+func setTlsConfig(t *testing.T, cfg *model.TlsConfig) {
+	t.Helper()
+	tlsCfg, authCfg := consts.Config.McpServer.Tls, consts.Config.McpServer.Auth
+	consts.Config.McpServer.Tls = cfg
+	consts.Config.McpServer.Auth = nil
+	t.Cleanup(func() {
+		consts.Config.McpServer.Tls = tlsCfg
+		consts.Config.McpServer.Auth = authCfg
+	})
+}
+
+// startTlsServer 以 buildTlsConfig 的结果启动 HTTPS 服务，响应中返回认证中间件识别出的调用方
+func startTlsServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	tlsConfig, err := buildTlsConfig()
+	if err != nil {
+		t.Fatalf("buildTlsConfig: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(auth.Auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity := auth.GetIdentity(r.Context())
+		_, _ = io.WriteString(w, identity.Method+":"+identity.Name+":"+identity.Profile)
+	})))
+	srv.TLS = tlsConfig
+	// 握手失败是预期结果，不输出到测试日志
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// tlsGet 发起 HTTPS 请求，cert 不为空时无论服务端接受哪些 CA 都发送该证书
+func tlsGet(srv *httptest.Server, roots *x509.CertPool, cert ...tls.Certificate) (string, error) {
+	tlsConfig := &tls.Config{RootCAs: roots}
+	if len(cert) > 0 {
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &cert[0], nil
+		}
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	defer client.CloseIdleConnections()
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestBuildTlsConfig(t *testing.T) {
+	pki := newTestPki(t)
+	invalidCa := pki.writePem(t, "invalid-ca.pem", "PRIVATE KEY", []byte("not a certificate"))
+
+	tests := []struct {
+		name       string
+		cfg        model.TlsConfig
+		wantErr    bool
+		clientAuth tls.ClientAuthType
+	}{
+		{name: "缺少证书", cfg: model.TlsConfig{Enabled: true, KeyFile: pki.keyFile}, wantErr: true},
+		{name: "证书不存在", cfg: model.TlsConfig{Enabled: true, CertFile: filepath.Join(pki.dir, "missing.pem"), KeyFile: pki.keyFile}, wantErr: true},
+		{name: "仅 HTTPS", cfg: model.TlsConfig{Enabled: true, CertFile: pki.certFile, KeyFile: pki.keyFile}, clientAuth: tls.NoClientCert},
+		{name: "默认 require", cfg: model.TlsConfig{Enabled: true, CertFile: pki.certFile, KeyFile: pki.keyFile, ClientCaFile: pki.caFile}, clientAuth: tls.RequireAndVerifyClientCert},
+		{name: "optional", cfg: model.TlsConfig{Enabled: true, CertFile: pki.certFile, KeyFile: pki.keyFile, ClientCaFile: pki.caFile, ClientAuth: "optional"}, clientAuth: tls.VerifyClientCertIfGiven},
+		{name: "未知 clientAuth", cfg: model.TlsConfig{Enabled: true, CertFile: pki.certFile, KeyFile: pki.keyFile, ClientCaFile: pki.caFile, ClientAuth: "any"}, wantErr: true},
+		{name: "CA 无有效证书", cfg: model.TlsConfig{Enabled: true, CertFile: pki.certFile, KeyFile: pki.keyFile, ClientCaFile: invalidCa}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			setTlsConfig(t, &cfg)
+			tlsConfig, err := buildTlsConfig()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("期望返回错误")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildTlsConfig: %v", err)
+			}
+			if tlsConfig.MinVersion != tls.VersionTLS12 {
+				t.Errorf("MinVersion = %x", tlsConfig.MinVersion)
+			}
+			if tlsConfig.ClientAuth != tt.clientAuth {
+				t.Errorf("ClientAuth = %v, want %v", tlsConfig.ClientAuth, tt.clientAuth)
+			}
+		})
+	}
+}
+
+func TestMutualTlsRequire(t *testing.T) {
+	pki := newTestPki(t)
+	setTlsConfig(t, &model.TlsConfig{
+		Enabled:        true,
+		CertFile:       pki.certFile,
+		KeyFile:        pki.keyFile,
+		ClientCaFile:   pki.caFile,
+		ClientProfiles: map[string]string{"sre-bot": "sre"},
+	})
+	srv := startTlsServer(t)
+
+	// 客户端证书 CN 映射为调用方名称，并按 clientProfiles 关联授权配置
+	body, err := tlsGet(srv, pki.caPool, pki.clientCert)
+	if err != nil {
+		t.Fatalf("携带可信客户端证书的请求失败: %v", err)
+	}
+	if body != "tls:sre-bot:sre" {
+		t.Errorf("identity = %q, want tls:sre-bot:sre", body)
+	}
+
+	if _, err = tlsGet(srv, pki.caPool); err == nil {
+		t.Error("require 模式下未携带客户端证书的请求应被拒绝")
+	}
+	if _, err = tlsGet(srv, pki.caPool, pki.otherCert); err == nil {
+		t.Error("不受信任 CA 签发的客户端证书应被拒绝")
+	}
+}
+
+func TestMutualTlsOptional(t *testing.T) {
+	pki := newTestPki(t)
+	setTlsConfig(t, &model.TlsConfig{
+		Enabled:      true,
+		CertFile:     pki.certFile,
+		KeyFile:      pki.keyFile,
+		ClientCaFile: pki.caFile,
+		ClientAuth:   "optional",
+	})
+	srv := startTlsServer(t)
+
+	body, err := tlsGet(srv, pki.caPool)
+	if err != nil {
+		t.Fatalf("optional 模式下未携带客户端证书的请求失败: %v", err)
+	}
+	if body != auth.Anonymous.Method+":"+auth.Anonymous.Name+":" {
+		t.Errorf("identity = %q, want anonymous", body)
+	}
+
+	body, err = tlsGet(srv, pki.caPool, pki.clientCert)
+	if err != nil {
+		t.Fatalf("携带可信客户端证书的请求失败: %v", err)
+	}
+	if body != "tls:sre-bot:" {
+		t.Errorf("identity = %q, want tls:sre-bot:", body)
+	}
+
+	// optional 只是允许不携带证书，携带的证书仍必须由可信 CA 签发
+	if _, err = tlsGet(srv, pki.caPool, pki.otherCert); err == nil {
+		t.Error("不受信任 CA 签发的客户端证书应被拒绝")
+	}
+}
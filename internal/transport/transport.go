@@ -0,0 +1,197 @@
+package transport
+
+import (
+	"ai-mcp/internal/auth"
+	"ai-mcp/internal/consts"
+	"ai-mcp/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcmd"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type sTransport struct {
+	httpServer   *http.Server
+	adminServer  *http.Server
+	sseServer    *server.SSEServer
+	shuttingDown atomic.Bool
+}
+
+var Transport = &sTransport{}
+
+// Mode 获取最终使用的传输方式，命令行参数 --transport 优先于配置 mcp-server.transport
+func (s *sTransport) Mode() (mode string, err error) {
+	mode = consts.Config.McpServer.Transport
+	if opt := gcmd.GetOpt("transport"); opt != nil && opt.String() != "" {
+		mode = opt.String()
+	}
+	if mode == "" {
+		mode = consts.TransportSSE
+	}
+	switch mode {
+	case consts.TransportStdio, consts.TransportSSE, consts.TransportStreamableHTTP, consts.TransportHTTP:
+		return
+	default:
+		err = fmt.Errorf("不支持的传输方式: %s（可选 stdio / sse / streamable-http / http）", mode)
+		return
+	}
+}
+
+// PrepareStdio stdio 模式下 stdout 专用于 JSON-RPC 消息，日志终端输出改写到 stderr
+func (s *sTransport) PrepareStdio() {
+	if consts.Logger.GetConfig().StdoutPrint {
+		consts.Logger.SetStdoutPrint(false)
+		consts.Logger.SetWriter(os.Stderr)
+	}
+	// gdb 默认使用独立 logger 且会输出到 stdout（debug: true 时打印 SQL），统一改用全局 logger
+	for _, group := range consts.DbGroups() {
+		g.DB(group).SetLogger(consts.Logger)
+	}
+}
+
+// Serve 按传输方式启动 MCP 服务，阻塞直到 ctx 取消（收到退出信号）或服务异常退出
+// ctx 取消后停止接受新的连接与会话，已建立的连接继续完成进行中的请求，需随后调用 Close 释放
+func (s *sTransport) Serve(ctx context.Context, mcpServer *server.MCPServer, mode string) error {
+	errCh := make(chan error, 2)
+
+	// 配置 adminAddress 时健康检查、指标等接口使用独立监听，否则与 MCP 服务共用监听
+	adminAddress := consts.Config.McpServer.AdminAddress
+	if adminAddress != "" {
+		adminMux := http.NewServeMux()
+		s.registerAdmin(adminMux, false)
+		s.adminServer = &http.Server{Addr: adminAddress, Handler: adminMux}
+		go func() {
+			errCh <- s.adminServer.ListenAndServe()
+		}()
+		consts.Logger.Infof(consts.Ctx, "管理接口已启动地址: http://%s", adminAddress)
+	}
+
+	if mode == consts.TransportStdio {
+		consts.Logger.Info(consts.Ctx, "MCP stdio 服务已启动")
+		stdioServer := server.NewStdioServer(mcpServer)
+		stdioServer.SetContextFunc(func(ctx context.Context) context.Context {
+			return auth.WithIdentity(ctx, auth.Stdio)
+		})
+		go func() {
+			errCh <- stdioServer.Listen(ctx, os.Stdin, os.Stdout)
+		}()
+	} else {
+		if err := s.startHttp(mcpServer, mode, adminAddress == "", errCh); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case err := <-errCh:
+		if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		// stdio 输入流结束（客户端退出）同样进入优雅关闭流程
+	case <-ctx.Done():
+	}
+	s.shuttingDown.Store(true)
+
+	// Shutdown 会立即关闭监听，不再接受新连接；SSE 长连接保持到 Close 时再断开
+	if s.httpServer != nil {
+		go func() {
+			_ = s.httpServer.Shutdown(context.Background())
+		}()
+	}
+	return nil
+}
+
+// startHttp 启动 SSE / Streamable HTTP 监听，withAdmin 为 true 时在同一监听上注册健康检查与指标接口
+func (s *sTransport) startHttp(mcpServer *server.MCPServer, mode string, withAdmin bool, errCh chan<- error) error {
+	address := consts.Config.McpServer.Address
+	mux := http.NewServeMux()
+	root := http.NewServeMux()
+	root.Handle("/", auth.Auth.Middleware(mux))
+	if withAdmin {
+		s.registerAdmin(root, true)
+	}
+	s.httpServer = &http.Server{
+		Addr:    address,
+		Handler: root,
+	}
+	if mode == consts.TransportSSE || mode == consts.TransportHTTP {
+		// 关联 HTTP 服务，SSEServer.Shutdown 时才会关闭全部 SSE 会话
+		s.sseServer = server.NewSSEServer(mcpServer, server.WithHTTPServer(s.httpServer))
+		mux.Handle(s.sseServer.CompleteSsePath(), s.sseServer)
+		mux.Handle(s.sseServer.CompleteMessagePath(), s.sseServer)
+		consts.Logger.Infof(consts.Ctx, "MCP SSE服务已启动地址: %s://%s%s", scheme(), address, s.sseServer.CompleteSsePath())
+	}
+	if mode == consts.TransportStreamableHTTP || mode == consts.TransportHTTP {
+		cfg := streamableHttpConfig()
+		mux.Handle(cfg.Path, streamableHttpHandler(mcpServer, cfg))
+		consts.Logger.Infof(consts.Ctx, "MCP Streamable HTTP服务已启动地址: %s://%s%s（stateless: %t）", scheme(), address, cfg.Path, cfg.Stateless)
+	}
+
+	if !tlsEnabled() {
+		go func() {
+			errCh <- s.httpServer.ListenAndServe()
+		}()
+		return nil
+	}
+	tlsConfig, err := buildTlsConfig()
+	if err != nil {
+		return err
+	}
+	s.httpServer.TLSConfig = tlsConfig
+	go func() {
+		errCh <- s.httpServer.ListenAndServeTLS("", "")
+	}()
+	return nil
+}
+
+// Close 断开 SSE 会话并关闭 HTTP 服务，在进行中的工具调用排空后调用
+func (s *sTransport) Close(ctx context.Context) {
+	if s.adminServer != nil {
+		_ = s.adminServer.Shutdown(ctx)
+	}
+	if s.httpServer == nil {
+		return
+	}
+	var err error
+	if s.sseServer != nil {
+		err = s.sseServer.Shutdown(ctx)
+	} else {
+		err = s.httpServer.Shutdown(ctx)
+	}
+	if err != nil {
+		_ = s.httpServer.Close()
+	}
+}
+
+// streamableHttpConfig 读取 Streamable HTTP 配置并补齐默认值
+func streamableHttpConfig() (cfg model.StreamableHttpConfig) {
+	if consts.Config.McpServer.StreamableHttp != nil {
+		cfg = *consts.Config.McpServer.StreamableHttp
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/mcp"
+	}
+	if cfg.SessionTTL <= 0 {
+		cfg.SessionTTL = 86400
+	}
+	return
+}
+
+// streamableHttpHandler 创建 Streamable HTTP 处理器，有状态模式下由 sessionIdManager 绑定调用方并提供断线续传
+func streamableHttpHandler(mcpServer *server.MCPServer, cfg model.StreamableHttpConfig) http.Handler {
+	opts := []server.StreamableHTTPOption{server.WithEndpointPath(cfg.Path)}
+	if cfg.HeartbeatInterval > 0 {
+		opts = append(opts, server.WithHeartbeatInterval(time.Duration(cfg.HeartbeatInterval)*time.Second))
+	}
+	if cfg.Stateless {
+		return server.NewStreamableHTTPServer(mcpServer, append(opts, server.WithStateLess(true))...)
+	}
+	sessions := newSessionIdManager(cfg.SessionSecret, time.Duration(cfg.SessionTTL)*time.Second)
+	return sessions.Handler(server.NewStreamableHTTPServer(mcpServer, append(opts, server.WithSessionIdManager(sessions))...))
+}
@@ -1,43 +1,118 @@
 package main
 
 import (
+	"ai-mcp/internal/audit"
 	"ai-mcp/internal/consts"
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	sysMcp "ai-mcp/internal/mcp"
+	"ai-mcp/internal/metrics"
+	"ai-mcp/internal/redact"
+	"ai-mcp/internal/tracing"
+	sysTransport "ai-mcp/internal/transport"
 
 	_ "github.com/gogf/gf/contrib/drivers/mysql/v2"
 	_ "github.com/gogf/gf/contrib/nosql/redis/v2"
-	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/glog"
 	"github.com/mark3labs/mcp-go/server"
 )
 
 func main() {
-	consts.Logger.Info(consts.Ctx, "ai-mcp start")
+	// 作为 RunSafeShellCommand 的沙箱初始化进程启动时，只执行命令，不启动服务
+	if code, ok := sysMcp.McpTool.RunSandboxInit(); ok {
+		os.Exit(code)
+	}
+
+	// 所有 logger（含 gdb 的 SQL 调试日志）输出前统一脱敏
+	glog.SetDefaultHandler(redact.LogHandler)
+
+	mode, err := sysTransport.Transport.Mode()
+	if err != nil {
+		panic(err)
+	}
+
+	// stdio 模式下 stdout 承载 JSON-RPC 消息，横幅与日志只能写到 stderr
+	var banner io.Writer = os.Stdout
+	if mode == consts.TransportStdio {
+		sysTransport.Transport.PrepareStdio()
+		banner = os.Stderr
+	}
+
+	consts.Logger.Infof(consts.Ctx, "ai-mcp start, version %s (%s)", consts.Version, consts.Commit)
 
 	// 启动MCP服务
 	// Create MCP server
+	if err = audit.Init(); err != nil {
+		panic(err)
+	}
+	shutdownTracing, err := tracing.Init(consts.Ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	hooks := &server.Hooks{}
+	metrics.RegisterHooks(hooks)
+	sysMcp.McpHandler.RegisterHooks(hooks)
 	s := server.NewMCPServer(
 		"MCP Server 🚀",
-		"1.0.0",
+		consts.Version,
+		server.WithToolFilter(sysMcp.McpHandler.ToolFilter),
+		server.WithHooks(hooks),
 	)
+	s.AddNotificationHandler("notifications/cancelled", sysMcp.McpHandler.HandleCancelled)
 
 	// Add tool
-	fmt.Printf("–––––––––––––––––––––––––––––––––MCP SERVER–––––––––––––––––––––––––––––––––\n\n")
-	for _, item := range sysMcp.McpHandler.GetList() {
-		fmt.Printf("添加工具 %s - %s\n", item.Name, item.Description)
-		s.AddTool(mcp.NewTool(item.Name,
-			append([]mcp.ToolOption{
-				mcp.WithDescription(item.Description),
-			}, item.ToolOptions...)...,
-		), sysMcp.McpHandler.GetMcpFn(&item))
+	fmt.Fprintf(banner, "–––––––––––––––––––––––––––––––––MCP SERVER–––––––––––––––––––––––––––––––––\n\n")
+	for _, item := range sysMcp.McpHandler.GetEnabledList() {
+		fmt.Fprintf(banner, "添加工具 %s - %s\n", item.Name, item.Description)
+		s.AddTool(sysMcp.McpHandler.NewTool(&item), sysMcp.McpHandler.GetMcpFn(&item))
 	}
-	fmt.Printf("\n––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––\n")
-
-	fmt.Printf("MCP SSE服务已启动地址: http://%s/sse\n", consts.Config.McpServer.Address)
+	fmt.Fprintf(banner, "\n––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––––\n")
 
-	// Start the stdio server
-	if err := server.NewSSEServer(s).Start(consts.Config.McpServer.Address); err != nil {
+	// Start the server with the selected transport
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err = sysTransport.Transport.Serve(ctx, s, mode); err != nil {
 		panic(err)
 	}
+	// 恢复默认的信号处理，等待期间再次收到 SIGINT/SIGTERM 时立即退出
+	stop()
+
+	// 优雅关闭：停止接受新会话，等待进行中的工具调用，超时后取消
+	shutdownTimeout := time.Duration(consts.Config.McpServer.ShutdownTimeout) * time.Second
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 30 * time.Second
+	}
+	consts.Logger.Infof(consts.Ctx, "收到退出信号，等待进行中的工具调用（最长 %s）", shutdownTimeout)
+	sysMcp.McpHandler.Drain(shutdownTimeout)
+
+	closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sysTransport.Transport.Close(closeCtx)
+	closeResources(closeCtx)
+	if err = shutdownTracing(closeCtx); err != nil {
+		consts.Logger.Warningf(closeCtx, "关闭链路追踪失败: %s", err.Error())
+	}
+	consts.Logger.Info(consts.Ctx, "ai-mcp stopped")
+}
+
+// closeResources 关闭数据库与 Redis 连接池
+func closeResources(ctx context.Context) {
+	for _, group := range consts.DbGroups() {
+		if err := g.DB(group).Close(ctx); err != nil {
+			consts.Logger.Warningf(ctx, "关闭数据库 %s 失败: %s", group, err.Error())
+		}
+	}
+	for _, name := range consts.RedisGroups() {
+		if err := g.Redis(name).Close(ctx); err != nil {
+			consts.Logger.Warningf(ctx, "关闭 Redis %s 失败: %s", name, err.Error())
+		}
+	}
 }
@@ -0,0 +1,13 @@
+package utility
+
+import "path"
+
+// MatchAny 判断名称是否匹配任一 glob 规则（语法同 path.Match，如 Base64*）
+func MatchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}